@@ -0,0 +1,88 @@
+"""Library API for running KrakenBuster scans from other Python programs.
+
+scan_targets_async() is the directory and vhost orchestration behind the
+combined command, and scan() and scan_async() run it for one target. By
+default they print nothing and return the results instead; the combined
+command passes its own console in the ScanSettings.
+"""
+
+from __future__ import annotations
+
+import asyncio
+
+from rich.console import Console
+
+from krakenbuster.runner import HostResult, ScanSettings, TargetSpec, run_combined
+from krakenbuster.scanners.base import Executor, exec_executor
+
+
+async def scan_targets_async(
+    targets: list[TargetSpec],
+    wordlist: str,
+    *,
+    dir_tool: str | None = None,
+    vhost_tool: str | None = None,
+    domain: str = "",
+    vhost_wordlist: str = "",
+    dir_options: dict[str, str] | None = None,
+    vhost_options: dict[str, str] | None = None,
+    concurrency: int = 1,
+    target_delay: float = 0.0,
+    settings: ScanSettings | None = None,
+) -> list[HostResult]:
+    """Scan each target with dir_tool and/or vhost_tool, returning the outcomes in target order.
+
+    Options are scanner options as the CLI builds them, with string values
+    (such as {"threads": "20", "depth": "2"}). The vhost scans fuzz
+    subdomains of the target's own domain, else domain, else the target's
+    host. Up to concurrency hosts are scanned at once, pausing target_delay
+    seconds after each host before starting the next (see
+    runner.run_combined()). A scan that fails, for example because its tool
+    is not installed, leaves its result None and its error in
+    HostResult.dir_error or vhost_error. settings default to a quiet
+    console and the real tools; warnings still go to the "krakenbuster"
+    logger. Raises ValueError if neither tool is given.
+    """
+    if not dir_tool and not vhost_tool:
+        raise ValueError("give dir_tool, vhost_tool or both")
+    return await run_combined(
+        targets, domain, dir_tool, vhost_tool, wordlist, vhost_wordlist,
+        dict(dir_options or {}), dict(vhost_options or {}), concurrency, target_delay,
+        settings or ScanSettings(console=Console(quiet=True)),
+    )
+
+
+async def scan_async(
+    target: str,
+    wordlist: str,
+    *,
+    dir_tool: str | None = None,
+    vhost_tool: str | None = None,
+    domain: str = "",
+    vhost_wordlist: str = "",
+    options: dict[str, str] | None = None,
+    output_dir: str = "./output",
+    executor: Executor | None = None,
+) -> HostResult:
+    """Scan target with dir_tool and/or vhost_tool and return both outcomes.
+
+    target must include its scheme. options are shared by both scans.
+    Output files are written under output_dir as for the CLI, and nothing
+    is printed. executor, if given, starts this call's tools in place of
+    exec_executor; concurrent calls may each use their own. Raises
+    ValueError if neither tool is given.
+    """
+    settings = ScanSettings(
+        console=Console(quiet=True), executor=executor or exec_executor, output_dir=output_dir
+    )
+    results = await scan_targets_async(
+        [TargetSpec(url=target)], wordlist, dir_tool=dir_tool, vhost_tool=vhost_tool,
+        domain=domain, vhost_wordlist=vhost_wordlist, dir_options=options,
+        vhost_options=options, settings=settings,
+    )
+    return results[0]
+
+
+def scan(target: str, wordlist: str, **kwargs) -> HostResult:
+    """Run scan_async() to completion; for callers without an event loop."""
+    return asyncio.run(scan_async(target, wordlist, **kwargs))
@@ -38,6 +38,7 @@ class KrakenBusterApp(App):
     target: str = ""
     wordlist_path: str = ""
     scan_options: dict[str, str] = {}
+    show_banner: bool = True
 
     def on_mount(self) -> None:
         """Check tool availability and push the welcome screen."""
@@ -3,8 +3,14 @@
 from __future__ import annotations
 
 import configparser
+import re
+import shlex
 from pathlib import Path
 
+from krakenbuster.log import logger
+from krakenbuster.output import DEFAULT_INTERESTING_KEYWORDS
+from krakenbuster.wordlist import DEFAULT_WORDLIST_EXTENSIONS, DiscoverOptions
+
 
 CONFIG_PATH = Path.home() / ".krakenbuster.conf"
 
@@ -17,11 +23,24 @@ DEFAULTS = {
     },
     "wordlists": {
         "last_used": "",
+        "last_filter": "",
+        "extensions": ".txt,.lst,.dic",
+        "include_extensionless": "false",
+    },
+    "display": {
+        "color_2xx": "",
+        "color_3xx": "",
+        "color_4xx": "",
+        "color_5xx": "",
+        "no_banner": "false",
+        "interesting_keywords": ",".join(DEFAULT_INTERESTING_KEYWORDS),
     },
     "tools": {
         "last_dir_tool": "feroxbuster",
         "last_vhost_tool": "ffuf",
         "last_dns_tool": "gobuster",
+        "feroxbuster_extra_args": "",
+        "ffuf_extra_args": "",
     },
 }
 
@@ -42,17 +61,90 @@ def load_config() -> configparser.ConfigParser:
             backup = CONFIG_PATH.with_suffix(".conf.bak")
             CONFIG_PATH.rename(backup)
             save_config(config)
-            print(
-                f"Migrated configuration: old file backed up to {backup}, "
-                f"new config written to {CONFIG_PATH}"
+            logger.warning(
+                "migrated configuration: old file backed up to %s, new config written to %s",
+                backup, CONFIG_PATH,
             )
     else:
         save_config(config)
-        print(f"Created default configuration at {CONFIG_PATH}")
+        logger.info("created default configuration at %s", CONFIG_PATH)
 
     return config
 
 
+STATUS_CLASSES = ("2xx", "3xx", "4xx", "5xx")
+
+_HEX_COLOUR = re.compile(r"^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$")
+
+
+def load_status_colours(config: configparser.ConfigParser) -> dict[str, str]:
+    """Read per-status-class colour overrides (color_2xx etc.) from the config.
+
+    Only valid hex colours (#rgb or #rrggbb) are returned; empty or invalid
+    values are left out so the default palette applies.
+    """
+    colours: dict[str, str] = {}
+    for status_class in STATUS_CLASSES:
+        value = config.get("display", f"color_{status_class}", fallback="").strip()
+        if not value:
+            continue
+        if _HEX_COLOUR.match(value):
+            colours[status_class] = value
+        else:
+            logger.warning("ignoring invalid colour for color_%s: %r", status_class, value)
+    return colours
+
+
+def load_extra_args(config: configparser.ConfigParser, tool: str) -> str:
+    """Read <tool>_extra_args from the [tools] section, for ffuf and feroxbuster.
+
+    Other tools have no such key and get ''. A value that cannot be split
+    like a shell command line is ignored with a warning.
+    """
+    if tool not in ("ffuf", "feroxbuster"):
+        return ""
+    value = config.get("tools", f"{tool}_extra_args", fallback="")
+    try:
+        shlex.split(value)
+    except ValueError as exc:
+        logger.warning("ignoring invalid %s_extra_args: %s", tool, exc)
+        return ""
+    return value
+
+
+def load_interesting_keywords(config: configparser.ConfigParser) -> tuple[str, ...]:
+    """Read the comma-separated interesting_keywords from the [display] section.
+
+    A missing key falls back to the built-in list; an empty value turns the
+    Interesting Findings section off.
+    """
+    value = config.get("display", "interesting_keywords", fallback=None)
+    if value is None:
+        return DEFAULT_INTERESTING_KEYWORDS
+    return tuple(k.strip() for k in value.split(",") if k.strip())
+
+
+def load_discover_options(config: configparser.ConfigParser) -> DiscoverOptions:
+    """Read wordlist discovery settings from the [wordlists] section."""
+    extensions = tuple(
+        "." + ext.strip().lstrip(".").lower()
+        for ext in config.get("wordlists", "extensions", fallback="").split(",")
+        if ext.strip().lstrip(".")
+    )
+    try:
+        include_extensionless = config.getboolean(
+            "wordlists", "include_extensionless", fallback=False
+        )
+    except ValueError:
+        value = config.get("wordlists", "include_extensionless")
+        logger.warning("ignoring invalid value for include_extensionless: %r", value)
+        include_extensionless = False
+    return DiscoverOptions(
+        extensions=extensions or DEFAULT_WORDLIST_EXTENSIONS,
+        include_extensionless=include_extensionless,
+    )
+
+
 def save_config(config: configparser.ConfigParser) -> None:
     """Write configuration to ~/.krakenbuster.conf."""
     with open(CONFIG_PATH, "w") as fh:
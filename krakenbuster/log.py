@@ -0,0 +1,111 @@
+"""Levelled logging to stderr for KrakenBuster's own warnings and diagnostics.
+
+Scan output stays on stdout; everything logged here goes to stderr, as
+coloured text for people or one JSON object per line for machines. With
+--json-logs, scan lifecycle events are also written to stderr as JSON lines.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+from datetime import datetime, timezone
+
+from rich.console import Console
+from rich.markup import escape
+
+logger = logging.getLogger("krakenbuster")
+
+# Lifecycle events for --json-logs, silent until configure_events() turns
+# them on; kept apart from logger so --log-level does not affect them
+events = logging.getLogger("krakenbuster.events")
+events.addHandler(logging.NullHandler())
+events.propagate = False
+
+# --log-level names, mapped to logging levels
+LOG_LEVELS = {
+    "error": logging.ERROR,
+    "warn": logging.WARNING,
+    "info": logging.INFO,
+    "debug": logging.DEBUG,
+}
+LOG_FORMATS = ("text", "json")
+
+# Label and Rich style for each level in text format
+_TEXT_STYLES = {
+    logging.ERROR: ("Error", "red"),
+    logging.WARNING: ("Warning", "yellow"),
+    logging.INFO: ("Info", "dim"),
+    logging.DEBUG: ("Debug", "dim"),
+}
+
+
+class TextHandler(logging.Handler):
+    """Write records to stderr in the CLI's "Warning: ..." style."""
+
+    def __init__(self) -> None:
+        super().__init__()
+        self.console = Console(stderr=True)
+
+    def emit(self, record: logging.LogRecord) -> None:
+        label, style = _TEXT_STYLES.get(record.levelno, ("Log", "dim"))
+        self.console.print(f"[{style}]{label}: {escape(record.getMessage())}[/{style}]")
+
+
+class JsonFormatter(logging.Formatter):
+    """Format records as single-line JSON objects.
+
+    Values passed as extra={"fields": {...}} are added as top-level keys.
+    """
+
+    def format(self, record: logging.LogRecord) -> str:
+        entry = {
+            "time": datetime.fromtimestamp(record.created, timezone.utc)
+            .isoformat(timespec="milliseconds")
+            .replace("+00:00", "Z"),
+            "level": record.levelname.lower(),
+            "message": record.getMessage(),
+        }
+        entry.update(getattr(record, "fields", {}))
+        return json.dumps(entry)
+
+
+def configure_logging(level: str = "warn", fmt: str = "text") -> None:
+    """Send the krakenbuster logger to stderr at the given --log-level and format.
+
+    Raises ValueError for an unknown level or format.
+    """
+    if level not in LOG_LEVELS:
+        raise ValueError(f"unknown log level: {level!r}")
+    if fmt not in LOG_FORMATS:
+        raise ValueError(f"unknown log format: {fmt!r}")
+
+    if fmt == "json":
+        handler: logging.Handler = logging.StreamHandler()
+        handler.setFormatter(JsonFormatter())
+    else:
+        handler = TextHandler()
+
+    logger.handlers[:] = [handler]
+    logger.setLevel(LOG_LEVELS[level])
+    logger.propagate = False
+
+
+def configure_events(enabled: bool) -> None:
+    """Turn the --json-logs event stream on stderr on or off."""
+    if enabled:
+        handler: logging.Handler = logging.StreamHandler()
+        handler.setFormatter(JsonFormatter())
+    else:
+        handler = logging.NullHandler()
+    events.handlers[:] = [handler]
+    events.setLevel(logging.INFO)
+
+
+def emit_event(event: str, **fields: object) -> None:
+    """Emit one --json-logs event, such as "finding", with fields as extra keys.
+
+    The "error" event is logged at error level, the rest at info.
+    """
+    level = logging.ERROR if event == "error" else logging.INFO
+    events.log(level, event, extra={"fields": {"event": event, **fields}})
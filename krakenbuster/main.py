@@ -7,30 +7,199 @@ Entry point with Click CLI handling for both interactive and non-interactive mod
 from __future__ import annotations
 
 import asyncio
+import json
 import os
+import re
+import shlex
 import shutil
+import sqlite3
 import sys
-import time
+from dataclasses import dataclass, field
+from datetime import datetime
+from pathlib import Path
+from typing import Callable, NoReturn
+from urllib.parse import urlparse
 
 import click
 from rich.console import Console
+from rich.panel import Panel
 from rich.table import Table
 
-from krakenbuster.config import load_config, update_config
+from krakenbuster.api import scan_targets_async
+from krakenbuster.config import load_config, load_discover_options, load_extra_args
+from krakenbuster.log import (
+    LOG_FORMATS,
+    LOG_LEVELS,
+    configure_events,
+    configure_logging,
+    logger,
+)
 from krakenbuster.output import (
     Finding,
+    RUN_TIMESTAMP_FORMAT,
+    SCHEMA_VERSION,
+    TAGS,
     ScanResult,
-    generate_output_paths,
-    append_raw_line,
-    write_json_results,
-    parse_finding,
+    expand_output_dir,
+    filter_findings,
+    load_finding_keys,
+    load_tags,
+    mask_credentials,
+    merge_finding_files,
+    sanitise_hostname,
+    unique_path,
+    build_envelope,
+    check_writable,
+    utc_timestamp,
+    write_merged,
+    write_metrics,
+    write_tags,
+    write_sqlite,
+    prune_runs,
+)
+from krakenbuster.probe import (
+    detect_extensions,
+    new_http_client,
+    probe_baseline,
+    probe_vhost_baseline,
+    rng,
+    seed_from_robots,
+    seed_from_sitemap,
+    seed_random,
+    resolve_scheme,
+)
+from krakenbuster.runner import (
+    HostResult,
+    ScanSettings,
+    TargetSpec,
+    merge_scan_results,
+    print_scan_summary,
+    run_cli_scan,
+    run_vhost_recursive,
+)
+from krakenbuster.scanners.helpers import (
+    bracket_ipv6,
+    check_header,
+    check_rate_threads,
+    check_request_file,
+    check_vhost_config,
+    default_scheme,
+    expand_cidr,
+    install_hint,
+    load_headers,
+    load_extensions,
+    normalise_extensions,
+    parse_duration,
+    parse_status_codes,
+    random_user_agent,
+    request_file_host,
+    signed_header,
+)
+from krakenbuster.wordlist import (
+    check_wordlist_path,
+    combine_wordlists,
+    discover_wordlists_cached,
+    get_all_files,
+    fetch_wordlist,
+    head_wordlist,
+    rank_for_target,
+    read_stdin_wordlist,
+    shuffle_wordlist,
+    target_relevance,
+    write_wordlist,
 )
-from krakenbuster.scanners.base import create_scanner
 
 
 console = Console()
 
 TOOLS = ["feroxbuster", "ffuf", "gobuster", "dirb", "wfuzz", "dirsearch", "amass", "subfinder"]
+# Output-line cap applied when --depth 0 (unlimited recursion) is used
+# without an explicit --max-output-lines, to stop runaway scans. Tools
+# mostly print findings, so this bounds what is found, not requests sent.
+UNLIMITED_DEPTH_LINE_CAP = 100_000
+
+# Most matching findings --interactive-filter lists for one query, unless
+# --display-rows says otherwise
+FILTER_ROWS = 50
+
+DIR_TOOLS = ["feroxbuster", "ffuf", "gobuster", "dirb", "wfuzz", "dirsearch"]
+VHOST_TOOLS = ["ffuf", "gobuster", "wfuzz"]
+
+# Process exit codes, documented in the README
+EXIT_OK = 0  # scan finished with findings
+EXIT_USAGE = 1  # bad flags or input files
+EXIT_TOOL_MISSING = 2  # the scan tool is not installed
+EXIT_SCAN_ERROR = 3  # the tool failed or the target was unreachable
+EXIT_NO_FINDINGS = 4  # scan finished with no findings, under --fail-on-empty
+EXIT_FINDINGS = 5  # findings tripped --fail-on-findings
+
+
+@dataclass
+class CliSettings:
+    """Process-wide settings for this invocation."""
+
+    no_banner: bool = False
+    quiet: bool = False
+    verbose: bool = False
+    # Fills {timestamp} in output directories, shared by every scan in the run
+    run_timestamp: str = field(default_factory=lambda: datetime.now().strftime(RUN_TIMESTAMP_FORMAT))
+
+
+settings = CliSettings()
+
+
+@dataclass
+class FindingsGate:
+    """--fail-on-findings: fail once this many findings match the status filter."""
+
+    threshold: int = 1
+    status_codes: list[int] | None = None  # None matches every status
+
+    def matching(self, scans: list[ScanResult]) -> int:
+        """Count the findings across scans that the gate applies to."""
+        return sum(
+            1
+            for scan in scans
+            for finding in scan.findings
+            if not self.status_codes or finding.status_code in self.status_codes
+        )
+
+
+def _scan_settings(**kwargs) -> ScanSettings:
+    """Build ScanSettings for a CLI scan: this console and the process-wide settings.
+
+    --quiet makes every scan summary-only, whatever the command's own flag.
+    """
+    kwargs["summary_only"] = kwargs.get("summary_only", False) or settings.quiet
+    return ScanSettings(
+        console=console,
+        banner=not settings.no_banner,
+        verbose=settings.verbose,
+        run_timestamp=settings.run_timestamp,
+        **kwargs,
+    )
+
+
+def _no_banner(flag: bool, quiet: bool) -> bool:
+    """Decide whether the banner is suppressed, by --no-banner, --quiet or no_banner in the config."""
+    if flag or quiet:
+        return True
+    try:
+        return load_config().getboolean("display", "no_banner", fallback=False)
+    except ValueError:
+        logger.warning("ignoring invalid no_banner value in the config")
+        return False
+
+
+def _run_tui() -> None:
+    """Launch the interactive TUI, then run any commands it returns."""
+    from krakenbuster.app import KrakenBusterApp
+    app = KrakenBusterApp()
+    app.show_banner = not settings.no_banner
+    result = app.run()
+
+    if result and isinstance(result, list):
+        _execute_commands(result)
 
 
 def check_tools() -> dict[str, bool]:
@@ -38,6 +207,16 @@ def check_tools() -> dict[str, bool]:
     return {tool: shutil.which(tool) is not None for tool in TOOLS}
 
 
+def _exit_tool_missing(tool: str) -> NoReturn:
+    """Report a missing scan tool with an install command for this system and exit."""
+    console.print(f"[red]Error: {tool} is not installed.[/red]")
+    if not os.environ.get("PATH"):
+        console.print("[dim]PATH is empty, so no tools can be found; set it and try again.[/dim]")
+    else:
+        console.print(f"[dim]Install with: {install_hint(tool)}[/dim]")
+    sys.exit(EXIT_TOOL_MISSING)
+
+
 def _execute_commands(commands: list[list[str]]) -> None:
     """Execute the command directly in the terminal after TUI exits."""
     if not commands:
@@ -50,173 +229,943 @@ def _execute_commands(commands: list[list[str]]) -> None:
         os.execvp(command[0], command)
     except FileNotFoundError:
         console.print(f"[bold red]Error:[/bold red] {command[0]} not found. Is it installed?")
-        sys.exit(1)
+        sys.exit(EXIT_TOOL_MISSING)
 
 
-async def run_cli_scan(
-    mode: str,
-    tool: str,
-    target: str,
-    wordlist: str,
-    options: dict,
-) -> None:
-    """Run a scan in non-interactive CLI mode with Rich output."""
-    config = load_config()
-    output_dir = config.get("general", "output_directory", fallback="./output")
-    raw_path, json_path = generate_output_paths(target, tool, mode, output_dir)
-
-    scanner = create_scanner(tool, mode, target, wordlist, options)
-    command = scanner.build_command()
-
-    console.print(f"\n[bold cyan]KrakenBuster[/bold cyan] - {tool} ({mode} mode)")
-    console.print(f"[dim]Target:[/dim]   {target}")
-    console.print(f"[dim]Wordlist:[/dim] {wordlist}")
-    console.print(f"[dim]Command:[/dim]  {' '.join(command)}\n")
-
-    result = ScanResult(
-        tool=tool,
-        mode=mode,
-        target=target,
-        wordlist=wordlist,
+def _interactive_filter(scans: list[ScanResult], rows: int = FILTER_ROWS) -> None:
+    """Prompt for filter queries and tags, listing the matching findings after a scan.
+
+    At most rows matches are listed per query (0 for all); this only limits
+    the display, never the output files. "t N TAG" tags row N of the last
+    listing with one of TAGS ("t N none" clears it); once the prompt ends,
+    the tags are saved to each scan's findings JSON and tags sidecar. Stops
+    at an empty line, q, Ctrl+C or end of input. Skipped unless stdin and
+    stdout are terminals.
+    """
+    findings = [f for scan in scans for f in scan.findings]
+    if not findings:
+        return
+    if not (sys.stdin.isatty() and console.is_terminal):
+        logger.warning("--interactive-filter needs a terminal, skipping")
+        return
+
+    console.print(
+        "\n[bold]Filter findings[/bold] [dim](status such as 200 or 3xx, size such as >1000, "
+        "tag:interesting, or text; t N TAG to tag row N as "
+        f"{', '.join(TAGS)} or none; empty line or q to finish)[/dim]"
     )
+    shown: list[Finding] = []
+    tagged = False
+    while True:
+        try:
+            query = console.input("[cyan]filter>[/cyan] ").strip()
+        except (EOFError, KeyboardInterrupt):
+            break
+        if query in ("", "q"):
+            break
+        if match := re.fullmatch(r"t\s+(\d+)\s+(\S+)", query):
+            row, tag = int(match.group(1)), match.group(2).lower()
+            if not 1 <= row <= len(shown):
+                console.print(f"[yellow]No row {row} in the last listing[/yellow]")
+            elif tag not in (*TAGS, "none"):
+                console.print(f"[yellow]Unknown tag {tag}; use {', '.join(TAGS)} or none[/yellow]")
+            else:
+                shown[row - 1].tag = "" if tag == "none" else tag
+                tagged = True
+            continue
+        matches = filter_findings(findings, query)
+        table = Table(title=f"{len(matches)} of {len(findings)} findings")
+        table.add_column("#", style="dim", justify="right")
+        table.add_column("Status Code", style="cyan", width=12)
+        table.add_column("Size", style="magenta", justify="right")
+        table.add_column("URL", style="white")
+        table.add_column("Tag", style="yellow")
+        shown = matches[:rows] if rows else matches
+        for row, finding in enumerate(shown, 1):
+            inputs = ", ".join(f"{k}={v}" for k, v in finding.inputs.items())
+            table.add_row(
+                str(row), str(finding.status_code), f"{finding.size:,}",
+                finding.url or inputs or "N/A", finding.tag,
+            )
+        console.print(table)
+        if len(shown) < len(matches):
+            console.print(
+                f"[dim](showing first {len(shown)} of {len(matches)}; "
+                "narrow the filter or raise --display-rows to see the rest)[/dim]"
+            )
+
+    if tagged:
+        _save_tags(scans)
+
+
+def _save_tags(scans: list[ScanResult]) -> None:
+    """Write the findings' tags back to each scan's findings JSON and tags sidecar."""
+    for scan in scans:
+        if not scan._json_path:
+            continue
+        try:
+            path = write_tags(Path(scan._json_path), scan.findings)
+        except (OSError, ValueError) as exc:
+            logger.warning("cannot save tags to %s: %s", scan._json_path, exc)
+        else:
+            console.print(f"[dim]Tags:[/dim] {path}")
+
+
+def _line_cap(depth: int, max_output_lines: int) -> int:
+    """Return the output-line cap to enforce, defaulting one for unlimited depth."""
+    if depth == 0 and max_output_lines == 0:
+        console.print(
+            f"[dim]Depth 0 means unlimited recursion; capping at "
+            f"{UNLIMITED_DEPTH_LINE_CAP:,} output lines (override with --max-output-lines)[/dim]"
+        )
+        return UNLIMITED_DEPTH_LINE_CAP
+    return max_output_lines
+
+
+def _shared_options(common: dict) -> dict[str, str]:
+    """Build the scanner options every mode shares from the common CLI flags."""
+    return {
+        "threads": str(common["threads"]),
+        "rate_limit": str(common["rate"]),
+        "proxy": common["proxy"],
+        "insecure": str(common["insecure"]).lower(),
+        "timeout": str(common["timeout"]),
+        "skip_empty": str(common["skip_empty"]).lower(),
+        "compress": str(common["compress"]).lower(),
+        "compact_json": str(common["compact_json"]).lower(),
+    }
+
+
+def _check_rate_threads(common: dict) -> None:
+    """Show a warning panel when --threads is out of proportion to --rate."""
+    warning = check_rate_threads(common["threads"], common["rate"])
+    if warning:
+        console.print(Panel(
+            warning, title="Check --threads and --rate", border_style="yellow", expand=False
+        ))
+
+
+def _status_range(min_status: int, max_status: int) -> dict[str, str]:
+    """Validate --min-status and --max-status, returning them as scan options."""
+    if min_status and max_status and min_status > max_status:
+        console.print(
+            f"[red]Error: --min-status {min_status} is above --max-status {max_status}.[/red]"
+        )
+        sys.exit(EXIT_USAGE)
+    return {"min_status": str(min_status), "max_status": str(max_status)}
+
 
-    start_time = time.time()
+def _resolve_extensions(common: dict) -> str:
+    """Merge --extensions with the contents of --extensions-file, if given."""
+    if not common["extensions_file"]:
+        return common["extensions"]
+    try:
+        return normalise_extensions(common["extensions"], load_extensions(common["extensions_file"]))
+    except OSError as exc:
+        console.print(f"[red]Error: cannot read extensions file: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+
+
+def _prepare_wordlist(
+    common: dict, options: dict[str, str], seeds: list[str] | None = None
+) -> tuple[str, Callable[[], None]]:
+    """Resolve --wordlist and --wordlist-url to one path, combining several if given.
+
+    A --wordlist of "-" reads the list from stdin into a temporary file.
+    seeds (from --use-robots) are put ahead of every wordlist.
+    With --shuffle-wordlist the result is copied in random order, following
+    --seed, and --wordlist-limit then keeps only its first entries.
+    """
+    wordlists = list(common["wordlist"])
+    cleanups: list[Callable[[], None]] = []
+
+    if common["wordlist_url"]:
+        try:
+            wordlists.append(fetch_wordlist(common["wordlist_url"], new_http_client(options)))
+        except (OSError, ValueError) as exc:
+            console.print(f"[red]Error: cannot fetch wordlist: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+    if not wordlists:
+        console.print("[red]Error: give --wordlist or --wordlist-url.[/red]")
+        sys.exit(EXIT_USAGE)
+
+    if wordlists.count("-") > 1:
+        console.print("[red]Error: --wordlist - can only be given once.[/red]")
+        sys.exit(EXIT_USAGE)
+    if "-" in wordlists:
+        if sys.stdin.isatty():
+            console.print("[red]Error: --wordlist - expects the wordlist piped on stdin.[/red]")
+            sys.exit(EXIT_USAGE)
+        try:
+            stdin_path, stdin_cleanup = read_stdin_wordlist(sys.stdin.buffer)
+        except (OSError, ValueError) as exc:
+            console.print(f"[red]Error: cannot read wordlist from stdin: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        wordlists[wordlists.index("-")] = stdin_path
+        cleanups.append(stdin_cleanup)
+    if seeds:
+        try:
+            seed_path, seed_cleanup = write_wordlist(seeds)
+        except OSError as exc:
+            for fn in cleanups:
+                fn()
+            console.print(f"[red]Error: cannot write seed paths: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        wordlists.insert(0, seed_path)
+        cleanups.append(seed_cleanup)
+
+    try:
+        for path in wordlists:
+            check_wordlist_path(path)
+        path, cleanup = combine_wordlists(wordlists)
+    except (OSError, ValueError) as exc:
+        for stdin_cleanup in cleanups:
+            stdin_cleanup()
+        console.print(f"[red]Error: cannot read wordlist: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+    cleanups.append(cleanup)
+    if len(wordlists) > 1:
+        console.print(f"[dim]Combined {len(wordlists)} wordlists into {path}[/dim]")
+    if common["shuffle_wordlist"]:
+        try:
+            path, cleanup = shuffle_wordlist(path, rng)
+        except OSError as exc:
+            for fn in cleanups:
+                fn()
+            console.print(f"[red]Error: cannot shuffle wordlist: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        cleanups.append(cleanup)
+        console.print(f"[dim]Shuffled wordlist into {path}[/dim]")
+    if common["wordlist_limit"]:
+        try:
+            path, cleanup = head_wordlist(path, common["wordlist_limit"])
+        except OSError as exc:
+            for fn in cleanups:
+                fn()
+            console.print(f"[red]Error: cannot limit wordlist: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        cleanups.append(cleanup)
+        console.print(f"[dim]Using the first {common['wordlist_limit']:,} wordlist entries[/dim]")
+
+    def cleanup_all() -> None:
+        for fn in cleanups:
+            fn()
+
+    return path, cleanup_all
+
+
+# Flags that contradict each other: (flag, other flag, why). Flags are
+# named by their parameter names; a flag counts as given when its value is
+# truthy, so only flags whose default is empty, 0 or off belong here.
+FLAG_CONFLICTS: list[tuple[str, str, str]] = [
+    ("url", "target_list", "give one target source"),
+    ("url", "target_cidr", "give one target source"),
+    ("target_list", "target_cidr", "give one target source"),
+    ("smart_wordlist", "wordlist", "--smart-wordlist picks the wordlist itself"),
+    ("smart_wordlist", "wordlist_url", "--smart-wordlist picks the wordlist itself"),
+    ("vhost_recurse", "request_file", "each nested scan needs its own Host, which a request file fixes"),
+    ("request_file", "headers", "ffuf takes the headers from the request file"),
+    ("request_file", "headers_file", "ffuf takes the headers from the request file"),
+    ("request_file", "random_agent", "ffuf takes the User-Agent from the request file"),
+    ("request_file", "hmac_key", "the signature header cannot be added to a request file"),
+    ("request_file", "extensions", "ffuf takes the path from the request file"),
+    ("request_file", "extensions_file", "ffuf takes the path from the request file"),
+    ("resume", "shuffle_wordlist", "a resumed scan must see the wordlist in its original order"),
+    ("stop_on_first", "interactive_filter", "a scan stopped at its first finding leaves nothing to filter"),
+    ("stop_on_first", "filter_redirect_loops", "a loop needs more than the one finding the scan stops at"),
+]
+
+# Flags that only work alongside another: (flag, needed flag, why)
+FLAG_REQUIRES: list[tuple[str, str, str]] = [
+    ("scan_secrets", "capture", "secrets are searched for in the captured responses"),
+    ("fail_status", "fail_on_findings", "it only narrows the findings --fail-on-findings counts"),
+    ("cidr_port", "target_cidr", "it sets the port of the --target-cidr hosts"),
+]
+
+# Status lists that must not share a code, as a finding cannot be both
+# kept and filtered out: (match flag, filter flag)
+STATUS_OVERLAPS: list[tuple[str, str]] = [
+    ("status_codes", "filter_codes"),
+    ("vhost_match_status", "filter_codes"),
+]
+
+
+def _flag_name(param: str) -> str:
+    """Return the command-line spelling of a parameter name."""
+    return "--" + param.replace("_", "-")
+
+
+def _check_flags() -> None:
+    """Reject contradictory flags before a command does any work.
+
+    Checks the current command's parameters against FLAG_CONFLICTS,
+    FLAG_REQUIRES and STATUS_OVERLAPS, and shows every problem found in one
+    panel before exiting.
+    """
+    params = click.get_current_context().params
+    problems = []
+    for flag, other, why in FLAG_CONFLICTS:
+        if params.get(flag) and params.get(other):
+            problems.append(f"{_flag_name(flag)} cannot be used with {_flag_name(other)}: {why}")
+    for flag, needed, why in FLAG_REQUIRES:
+        if params.get(flag) and not params.get(needed):
+            problems.append(f"{_flag_name(flag)} needs {_flag_name(needed)}: {why}")
+    for match, filtered in STATUS_OVERLAPS:
+        try:
+            both = set(parse_status_codes(params.get(match) or "")) & set(
+                parse_status_codes(params.get(filtered) or "")
+            )
+        except ValueError:
+            continue  # reported by the flag's own validation
+        if both:
+            codes = ", ".join(map(str, sorted(both)))
+            problems.append(f"{_flag_name(match)} and {_flag_name(filtered)} both list {codes}")
+    if problems:
+        console.print(Panel(
+            "\n".join(problems), title="Conflicting options", border_style="red", expand=False
+        ))
+        sys.exit(EXIT_USAGE)
+
+
+def _claim_stdout(common: dict) -> None:
+    """With --output-stdout, move all console output to stderr.
+
+    stdout is then left for the single document _write_stdout() prints at
+    the end, so it can be piped straight into jq.
+    """
+    if common["output_stdout"]:
+        console.file = sys.stderr
+
+
+def _write_sqlite_file(path: str, results: list[ScanResult]) -> None:
+    """Add results to the --sqlite database, if requested, warning on errors."""
+    if not path:
+        return
+    try:
+        write_sqlite(Path(path), results)
+    except sqlite3.Error as exc:
+        logger.warning("cannot write SQLite database %s: %s", path, exc)
+    else:
+        console.print(f"[dim]SQLite:[/dim] {path}")
 
-    process = await asyncio.create_subprocess_exec(
-        *command,
-        stdout=asyncio.subprocess.PIPE,
-        stderr=asyncio.subprocess.PIPE,
+
+def _write_stdout(common: dict, results: list[ScanResult]) -> None:
+    """Print every scan's results to stdout as one JSON document, if requested."""
+    if common["output_stdout"] != "json":
+        return
+    document = {
+        "schema_version": SCHEMA_VERSION,
+        "scans": [build_envelope(scan.findings, scan._meta, scan.secrets) for scan in results],
+    }
+    sys.stdout.write(json.dumps(document, indent=2) + "\n")
+    sys.stdout.flush()
+
+
+def _write_metrics_file(path: str, results: list[ScanResult]) -> None:
+    """Write --metrics-file, if requested, warning rather than failing on errors."""
+    if not path:
+        return
+    try:
+        write_metrics(Path(path), results)
+    except OSError as exc:
+        logger.warning("cannot write metrics file: %s", exc)
+    else:
+        console.print(f"[dim]Metrics:[/dim] {path}")
+
+
+def _load_baseline(common: dict) -> tuple[set[str], dict[str, str]]:
+    """Load the finding keys and tags of the --baseline findings file, empty if none was given.
+
+    Not to be confused with the soft-404 Baseline that --auto-filter probes.
+    """
+    if not common["baseline_file"]:
+        return set(), {}
+    try:
+        return load_finding_keys(common["baseline_file"]), load_tags(common["baseline_file"])
+    except (OSError, ValueError) as exc:
+        console.print(f"[red]Error: cannot read baseline file: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+
+
+def _findings_gate(common: dict) -> FindingsGate | None:
+    """Build the --fail-on-findings gate, validating its status filter up front."""
+    if not common["fail_on_findings"]:
+        return None
+    try:
+        status_codes = parse_status_codes(common["fail_status"])
+    except ValueError as exc:
+        console.print(f"[red]Error: --fail-status: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+    return FindingsGate(threshold=common["fail_threshold"], status_codes=status_codes or None)
+
+
+def _exit_for_results(
+    scans: list[ScanResult],
+    fail_on_empty: bool,
+    gate: FindingsGate | None = None,
+    errored: bool = False,
+) -> NoReturn:
+    """Exit with the code describing how the scans went.
+
+    A failed tool (or errored is True) comes first, then the findings gate,
+    then an empty result, which only counts when --fail-on-empty was given.
+    """
+    if errored or any(scan.failed for scan in scans):
+        sys.exit(EXIT_SCAN_ERROR)
+    if gate:
+        matched = gate.matching(scans)
+        if matched >= gate.threshold:
+            console.print(
+                f"[red]{matched} finding(s) matched --fail-on-findings "
+                f"(threshold {gate.threshold})[/red]"
+            )
+            sys.exit(EXIT_FINDINGS)
+    if fail_on_empty and not any(scan.findings for scan in scans):
+        sys.exit(EXIT_NO_FINDINGS)
+    sys.exit(EXIT_OK)
+
+
+def _output_dir_template(common: dict) -> str:
+    """Return the --output-dir template, checking it suits --keep-runs."""
+    template = common["output_dir"] or load_config().get(
+        "general", "output_directory", fallback="./output"
     )
+    if common["keep_runs"] and Path(template).name != "{timestamp}":
+        console.print("[red]Error: --keep-runs needs an output directory ending in {timestamp}.[/red]")
+        sys.exit(EXIT_USAGE)
 
-    assert process.stdout is not None
-    assert process.stderr is not None
+    # Check the part before any {host} or {timestamp} token now, rather than
+    # when the first result is written
+    static = Path(".")
+    for part in Path(template).parts:
+        if "{" in part:
+            break
+        static /= part
+    try:
+        check_writable(static)
+    except OSError as exc:
+        console.print(f"[red]Error: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+    return template
 
-    async def read_stdout() -> None:
-        async for raw_line in process.stdout:
-            line = raw_line.decode("utf-8", errors="replace").rstrip()
-            if not line:
-                continue
 
-            result.raw_lines.append(line)
-            await append_raw_line(raw_path, line)
+def _prune_old_runs(template: str, targets: list[str], keep: int) -> None:
+    """Apply --keep-runs to the run directories of each target's host."""
+    if not keep:
+        return
+    for base in {Path(template.replace("{host}", sanitise_hostname(t))).parent for t in targets}:
+        try:
+            removed = prune_runs(base, keep)
+        except OSError as exc:
+            logger.warning("cannot prune old runs in %s: %s", base, exc)
+            continue
+        for path in removed:
+            logger.info("removed old run %s", path)
 
-            finding = parse_finding(line)
-            if finding:
-                result.findings.append(finding)
-                status = finding.status_code
-                colour = _status_colour(status)
-                console.print(f"[{colour}][{status}][/{colour}] {line}")
-            else:
-                console.print(f"[dim]{line}[/dim]")
 
-    async def read_stderr() -> None:
-        async for raw_line in process.stderr:
-            line = raw_line.decode("utf-8", errors="replace").rstrip()
-            if line:
-                result.stderr_lines.append(line)
+def _with_scheme(target: str, auto_scheme: bool, options: dict[str, str]) -> str:
+    """Return target with a scheme, probing https then http for bare hosts."""
+    if "://" in target:
+        return target
+    if not auto_scheme:
+        console.print(f"[red]Error: target must begin with http:// or https://: {target}[/red]")
+        sys.exit(EXIT_USAGE)
+    target = bracket_ipv6(target)
+    try:
+        url = resolve_scheme(target, new_http_client(options))
+    except OSError as exc:
+        console.print(f"[red]Error: {exc}[/red]")
+        sys.exit(EXIT_SCAN_ERROR)
+    console.print(f"[dim]No scheme given, using {url}[/dim]")
+    return url
 
-    await asyncio.gather(read_stdout(), read_stderr())
-    await process.wait()
 
-    result.duration_seconds = time.time() - start_time
-    await write_json_results(json_path, result.findings)
+def _resolve_headers(headers: tuple[str, ...], headers_file: str, random_agent: bool = False) -> str:
+    """Merge --headers-file and --header values into the "headers" option.
 
-    # Print summary
-    console.print(f"\n[bold cyan]Scan Complete[/bold cyan]")
-    console.print(f"Duration: {result.duration_formatted}")
-    console.print(f"Findings: [bold green]{len(result.findings)}[/bold green]")
+    With random_agent, a User-Agent from the built-in pool is added unless
+    one of the headers already sets it.
+    """
+    merged: list[str] = []
+    if headers_file:
+        try:
+            merged.extend(load_headers(headers_file))
+        except OSError as exc:
+            console.print(f"[red]Error: cannot read headers file: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        except ValueError as exc:
+            console.print(f"[red]Error: --headers-file: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+    for header in headers:
+        try:
+            merged.append(check_header(header))
+        except ValueError as exc:
+            console.print(f"[red]Error: --header: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+    if random_agent:
+        if any(h.partition(":")[0].strip().lower() == "user-agent" for h in merged):
+            logger.warning("--random-agent ignored, a User-Agent header was given")
+        else:
+            agent = random_user_agent(rng)
+            console.print(f"[dim]User-Agent:[/dim] {agent}")
+            merged.append(f"User-Agent: {agent}")
+    return "\n".join(merged)
 
-    if result.findings:
-        table = Table(title="Findings Breakdown")
-        table.add_column("Status Code", style="cyan", width=12)
-        table.add_column("Count", style="green", width=8)
-        table.add_column("Example URL", style="white")
 
-        for status, items in sorted(result.findings_by_status.items()):
-            example = items[0].url if items[0].url else "N/A"
-            table.add_row(str(status), str(len(items)), example)
+def _add_signed_header(options: dict[str, str], key: str, name: str, target: str) -> None:
+    """Add the --hmac-key signature of target to the "headers" option, if a key was given."""
+    if not key:
+        return
+    try:
+        header = check_header(signed_header(key, target, name))
+    except ValueError as exc:
+        console.print(f"[red]Error: --hmac-header: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+    options["headers"] = "\n".join(filter(None, [options["headers"], header]))
+    console.print(f"[dim]{name} header: HMAC-SHA256 of {target}[/dim]")
+
+
+def _compile_patterns(patterns: tuple[str, ...], flag: str) -> list[re.Pattern[str]]:
+    """Compile regex flag values up front so a typo fails before the scan."""
+    compiled = []
+    for pattern in patterns:
+        try:
+            compiled.append(re.compile(pattern))
+        except re.error as exc:
+            console.print(f"[red]Error: {flag}: invalid regex {pattern!r}: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+    return compiled
 
-        console.print(table)
 
-    console.print(f"\n[dim]Raw output:[/dim]  {raw_path}")
-    console.print(f"[dim]JSON output:[/dim] {json_path}")
+def _check_http2(tool: str, targets: list[str]) -> None:
+    """Warn when --http2 cannot take effect for this tool or any of these targets."""
+    if tool != "ffuf":
+        logger.warning("--http2 is only supported by ffuf, %s will use its default protocol", tool)
+    elif any(target.lower().startswith("http://") for target in targets):
+        logger.warning(
+            "--http2 over plain http needs h2c support on the target; "
+            "most servers only offer HTTP/2 over https"
+        )
 
-    if result.stderr_lines:
-        console.print("\n[bold red]Warnings/Errors:[/bold red]")
-        for line in result.stderr_lines[-10:]:
-            console.print(f"  [red]{line}[/red]")
 
+def _robots_seeds(url: str, options: dict[str, str]) -> list[str]:
+    """Collect --use-robots seed paths from the target's robots.txt and sitemap.xml."""
+    client = new_http_client(options)
+    seeds: list[str] = []
+    for name, fetch in (("robots.txt", seed_from_robots), ("sitemap.xml", seed_from_sitemap)):
+        try:
+            found = fetch(url, client)
+        except OSError as exc:
+            logger.warning("cannot fetch %s: %s", name, exc)
+            continue
+        console.print(f"[dim]{name}: {len(found)} paths[/dim]")
+        seeds.extend(found)
+    return list(dict.fromkeys(seeds))
+
+
+def _smart_wordlist(technologies: list[str]) -> str:
+    """Pick the installed wordlist best suited to the detected technologies.
+
+    Falls back to a recommended directory wordlist when none matches.
+    """
+    files = get_all_files(discover_wordlists_cached(None, load_discover_options(load_config())))
+    ranked = rank_for_target(files, technologies)
+    if not ranked:
+        console.print("[red]Error: --smart-wordlist found no installed wordlists; give --wordlist.[/red]")
+        sys.exit(EXIT_USAGE)
+    choice = ranked[0]
+    if target_relevance(choice.path, technologies):
+        console.print(f"[dim]Smart wordlist for {', '.join(technologies)}: {choice.path}[/dim]")
+    else:
+        console.print(f"[dim]No wordlist matches the detected technology, using {choice.path}[/dim]")
+    return str(choice.path)
 
-def _status_colour(code: int) -> str:
-    """Return a Rich colour name for an HTTP status code."""
-    if code == 200:
-        return "green"
-    elif code in (301, 302, 307):
-        return "yellow"
-    elif code in (401, 403):
-        return "cyan"
-    elif code >= 500:
-        return "red"
-    return "white"
+
+def _keyword_wordlists(pairs: tuple[str, ...], tool: str) -> str:
+    """Validate --wordlist-keyword PATH:KEYWORD pairs for the options dict."""
+    if not pairs:
+        return ""
+    if tool != "ffuf":
+        console.print("[red]Error: --wordlist-keyword is only supported with ffuf.[/red]")
+        sys.exit(EXIT_USAGE)
+    for pair in pairs:
+        path, _, keyword = pair.rpartition(":")
+        if not path or not keyword.isidentifier() or keyword == "FUZZ":
+            console.print(
+                f"[red]Error: --wordlist-keyword must be PATH:KEYWORD with a keyword "
+                f"other than FUZZ, got {pair!r}[/red]"
+            )
+            sys.exit(EXIT_USAGE)
+        try:
+            check_wordlist_path(path)
+        except (OSError, ValueError) as exc:
+            console.print(f"[red]Error: cannot read wordlist: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+    return "\n".join(pairs)
+
+
+def _request_file(path: str, tool: str) -> str:
+    """Validate --request-file for the options dict, returning its absolute path."""
+    if not path:
+        return ""
+    if tool != "ffuf":
+        console.print("[red]Error: --request-file is only supported with ffuf.[/red]")
+        sys.exit(EXIT_USAGE)
+    try:
+        check_request_file(path)
+    except OSError as exc:
+        console.print(f"[red]Error: cannot read request file: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+    except ValueError as exc:
+        console.print(f"[red]Error: --request-file: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+    return os.path.abspath(path)
+
+
+def _check_request_host(path: str, domain: str) -> None:
+    """Warn when a vhost --request-file fuzzes a Host outside --domain.
+
+    ffuf sends the file's Host, so --domain then only steers KrakenBuster's
+    own checks, such as the --auto-filter probe.
+    """
+    try:
+        host = request_file_host(path).lower()
+    except OSError:
+        return  # reported when the file was validated
+    if not host.rsplit(":", 1)[0].endswith("." + domain.lower()):
+        logger.warning(
+            "the request file's Host %r is not a subdomain of --domain %s; ffuf fuzzes the "
+            "file's Host, and --domain is only used for KrakenBuster's own checks",
+            host, domain,
+        )
+
+
+def _replay_proxy(url: str, tool: str) -> str:
+    """Validate --replay-proxy for the options dict."""
+    if not url:
+        return ""
+    if tool != "ffuf":
+        console.print("[red]Error: --replay-proxy is only supported with ffuf.[/red]")
+        sys.exit(EXIT_USAGE)
+    parsed = urlparse(url)
+    if parsed.scheme not in ("http", "https", "socks5") or not parsed.hostname:
+        console.print(
+            f"[red]Error: --replay-proxy must be an http://, https:// or socks5:// URL, "
+            f"got {mask_credentials(url)!r}[/red]"
+        )
+        sys.exit(EXIT_USAGE)
+    return url
+
+
+def _extra_args(tool: str, ffuf_args: tuple[str, ...], ferox_args: tuple[str, ...]) -> str:
+    """Merge the config's <tool>_extra_args with --ffuf-arg/--ferox-arg for the options dict.
+
+    Each value is split like a shell command line, so one flag can carry
+    its argument ("-mc all"). Flags for a tool other than the one in use
+    are ignored with a warning.
+    """
+    given = {"ffuf": ffuf_args, "feroxbuster": ferox_args}
+    for name, flag in (("ffuf", "--ffuf-arg"), ("feroxbuster", "--ferox-arg")):
+        if given[name] and tool != name:
+            logger.warning("%s only applies to %s, ignoring it for %s", flag, name, tool)
+    if tool not in given:
+        return ""
+    values = [load_extra_args(load_config(), tool), *given[tool]]
+    try:
+        return shlex.join(token for value in values for token in shlex.split(value))
+    except ValueError as exc:
+        console.print(f"[red]Error: cannot parse extra {tool} arguments: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
 
 
 def _common_options(func):
     """Shared CLI options across scan modes."""
-    func = click.option("--wordlist", "-w", required=True, help="Path to wordlist file")(func)
+    func = click.option("--wordlist", "-w", multiple=True,
+                        help="Path to wordlist file (repeat to combine several)")(func)
+    func = click.option("--wordlist-limit", default=0, type=click.IntRange(min=0),
+                        help="Use only the first N wordlist entries, e.g. for a smoke test (0 for all)")(func)
+    func = click.option("--shuffle-wordlist", is_flag=True,
+                        help="Send wordlist entries in random order (repeatable with --seed)")(func)
+    func = click.option("--wordlist-url", default="",
+                        help="Download the wordlist from this URL (cached between runs)")(func)
     func = click.option("--threads", "-t", default=50, help="Number of threads")(func)
     func = click.option("--rate", "-r", default=200, help="Rate limit (requests per second)")(func)
     func = click.option("--proxy", default="", help="Proxy URL")(func)
+    func = click.option("--insecure", "-k", is_flag=True, help="Skip TLS certificate verification")(func)
+    func = click.option("--timeout", default=10, help="Timeout in seconds for KrakenBuster's own HTTP probes")(func)
+    func = click.option("--output-dir", "-o", default="",
+                        help="Output directory, may use {host} and {timestamp} (default from the config)")(func)
+    func = click.option("--keep-runs", default=0, type=click.IntRange(min=0),
+                        help="Keep only the newest N {timestamp} run directories per host")(func)
+    func = click.option("--output-stdout", default="", type=click.Choice(["", "json"]),
+                        help="Print all results as one JSON document on stdout, other output on stderr")(func)
+    func = click.option("--metrics-file", default="", help="Write Prometheus text-format metrics here at scan end")(func)
+    func = click.option("--sqlite", "sqlite_file", default="",
+                        help="Also add runs and findings to this SQLite database")(func)
+    func = click.option("--baseline", "baseline_file", default="",
+                        help="Findings JSON from an earlier run; report only findings not in it")(func)
+    func = click.option("--compress", is_flag=True,
+                        help="Gzip the raw, JSON and --keep-raw output files once the scan ends; "
+                             "an interrupted scan leaves them uncompressed")(func)
+    func = click.option("--compact-json", is_flag=True,
+                        help="Write findings JSON on one line instead of indented")(func)
+    func = click.option("--skip-empty", is_flag=True,
+                        help="Write no output files for a scan that finds nothing")(func)
+    func = click.option("--legacy-json", is_flag=True, help="Write findings JSON as a bare array (deprecated)")(func)
+    func = click.option("--fail-on-empty", is_flag=True, help="Exit with code 4 when the scan finds nothing")(func)
+    func = click.option("--fail-on-findings", is_flag=True,
+                        help="Exit with code 5 when findings match (for CI gating)")(func)
+    func = click.option("--fail-threshold", default=1, type=click.IntRange(min=1),
+                        help="Findings needed to trip --fail-on-findings")(func)
+    func = click.option("--fail-status", default="",
+                        help="Only count findings with these status codes for --fail-on-findings")(func)
+    return func
+
+
+def _extension_options(func):
+    """Extension options, for the modes that request paths (dir and combined)."""
     func = click.option("--extensions", "-x", default="", help="File extensions to test (comma-separated)")(func)
-    func = click.option("--output-dir", "-o", default="./output", help="Output directory")(func)
+    func = click.option("--extensions-file", default="", help="File of extensions (comma- or newline-separated)")(func)
     return func
 
 
-@click.group(invoke_without_command=True)
+def _request_options(func):
+    """Options shaping the requests sent, for every scan mode."""
+    func = click.option("--http2", is_flag=True, help="ffuf only: send requests over HTTP/2")(func)
+    func = click.option("--random-agent", is_flag=True,
+                        help="Send a random browser User-Agent, chosen once per run")(func)
+    func = click.option("--headers-file", default="",
+                        help="File of extra request headers, one 'Name: Value' per line")(func)
+    func = click.option("--header", "-H", "headers", multiple=True,
+                        help="Extra request header as 'Name: Value' (repeatable)")(func)
+    func = click.option("--auto-scheme/--no-auto-scheme", default=True,
+                        help="Probe https then http when the target has no scheme")(func)
+    return func
+
+
+def _display_options(func):
+    """Options for how findings are shown on the terminal, for every scan mode."""
+    func = click.option("--display-rows", default=FILTER_ROWS, type=click.IntRange(min=0),
+                        help="Most findings --interactive-filter lists per query (0 for all); "
+                             "files keep everything")(func)
+    func = click.option("--interactive-filter", is_flag=True,
+                        help="After the scan, prompt for filters to narrow the findings")(func)
+    func = click.option("--summary-only", is_flag=True, help="Do not echo each finding; print only the summary")(func)
+    return func
+
+
+class _ExitCodeGroup(click.Group):
+    """Click group that reports usage errors with EXIT_USAGE.
+
+    Click exits with 2 for bad flags, which would clash with
+    EXIT_TOOL_MISSING.
+    """
+
+    def main(self, *args, **kwargs):
+        try:
+            return super().main(*args, standalone_mode=False, **kwargs)
+        except click.ClickException as exc:
+            exc.show()
+            sys.exit(EXIT_USAGE)
+        except click.Abort:
+            click.echo("Aborted!", err=True)
+            sys.exit(EXIT_USAGE)
+
+
+@click.group(cls=_ExitCodeGroup, invoke_without_command=True)
+@click.option("--no-banner", is_flag=True, help="Do not print the banner (also set by no_banner in the config)")
+@click.option("--quiet", "-q", is_flag=True,
+              help="Print no banner or tool output lines, only progress, warnings and summaries")
+@click.option("--verbose", "-v", is_flag=True,
+              help="Show each tool command and its environment before it runs")
+@click.option("--log-level", default="warn", type=click.Choice(list(LOG_LEVELS)),
+              help="Least severe stderr log messages to show")
+@click.option("--log-format", default="text", type=click.Choice(LOG_FORMATS),
+              help="Log as coloured text or one JSON object per line")
+@click.option("--json-logs", is_flag=True,
+              help="Also write scan events (start, findings, progress, end, errors) to stderr as JSON lines")
+@click.option("--seed", type=int, default=None,
+              help="Seed random probe paths, Host names and User-Agents so runs can be reproduced")
 @click.pass_context
-def cli(ctx: click.Context) -> None:
+def cli(
+    ctx: click.Context, no_banner: bool, quiet: bool, verbose: bool, log_level: str,
+    log_format: str, json_logs: bool, seed: int | None,
+) -> None:
     """KrakenBuster: guided web enumeration tool for penetration testing.
 
     Run without a subcommand to launch the interactive TUI.
-    Use subcommands (dir, vhost, dns) for non-interactive mode.
+    Use subcommands (dir, vhost, dns, combined) for non-interactive mode.
     """
-    if ctx.invoked_subcommand is None:
-        from krakenbuster.app import KrakenBusterApp
-        app = KrakenBusterApp()
-        result = app.run()
+    configure_logging(log_level, log_format)
+    configure_events(json_logs)
+    settings.verbose = verbose
+    settings.quiet = quiet
+    logger.debug("argv: %s", shlex.join(sys.argv), extra={"fields": {"argv": sys.argv}})
+    if seed is not None:
+        seed_random(seed)
+        logger.debug("random seed: %d", seed)
 
-        if result and isinstance(result, list):
-            _execute_commands(result)
+    settings.no_banner = _no_banner(no_banner, quiet)
+
+    if ctx.invoked_subcommand is None:
+        _run_tui()
 
 
 @cli.command()
 @click.option("--tool", required=True, type=click.Choice(TOOLS), help="Scanner tool to use")
 @click.option("--url", required=True, help="Target URL")
 @_common_options
-@click.option("--depth", default=3, help="Recursion depth")
+@_extension_options
+@_request_options
+@_display_options
+@click.option("--hmac-key", default="", envvar="KRAKENBUSTER_HMAC_KEY",
+              help="Send an HMAC-SHA256 of the target URL under this key as a header")
+@click.option("--hmac-header", default="X-Signature", help="Header name for the --hmac-key signature")
+@click.option("--depth", default=3, type=click.IntRange(min=0), help="Recursion depth (0 for unlimited)")
+@click.option("--max-output-lines", default=0, type=click.IntRange(min=0),
+              help="Stop after the tool prints this many lines, not requests (0 for no cap)")
+@click.option("--stop-on-first", is_flag=True, help="Stop the scan at the first finding and report only that one")
 @click.option("--status-codes", default="", help="Status codes to include (comma-separated)")
 @click.option("--filter-codes", default="", help="Status codes to filter out (comma-separated)")
+@click.option("--min-status", default=0, type=click.IntRange(min=0, max=599),
+              help="Only keep findings with at least this status code")
+@click.option("--max-status", default=0, type=click.IntRange(min=0, max=599),
+              help="Only keep findings with at most this status code")
 @click.option("--filter-size", default="", help="Filter response size")
-def dir(tool, url, wordlist, threads, rate, proxy, extensions, output_dir,
-        depth, status_codes, filter_codes, filter_size):
+@click.option("--auto-filter", is_flag=True, help="Probe a random path and filter soft-404 responses")
+@click.option("--smart-extensions", is_flag=True, help="Add extensions for the technology the target appears to use")
+@click.option("--use-robots", is_flag=True,
+              help="Put paths from the target's robots.txt and sitemap.xml at the front of the wordlist")
+@click.option("--smart-wordlist", is_flag=True,
+              help="Pick an installed wordlist for the technology the target appears to use")
+@click.option("--resume", is_flag=True, help="Keep feroxbuster state and resume an interrupted scan")
+@click.option("--capture", is_flag=True, help="Save headers and body start of 200/5xx findings")
+@click.option("--capture-bytes", default=4096, type=click.IntRange(min=0), help="Body bytes to keep per capture")
+@click.option("--exclude-url-regex", multiple=True, help="Drop findings whose URL matches this regex (repeatable)")
+@click.option("--scope-regex", default="", help="Drop findings whose URL does not match this regex")
+@click.option("--filter-redirect-loops", is_flag=True,
+              help="Drop redirects that point back to themselves or loop between findings")
+@click.option("--hash-bodies", is_flag=True, help="Hash each 200 response body and group paths serving identical pages")
+@click.option("--scan-secrets", is_flag=True,
+              help="Search captured responses for API keys, JWTs and similar (needs --capture)")
+@click.option("--keep-raw", is_flag=True,
+              help="Keep the tool's own JSON output in <output-dir>/raw/ (ffuf, feroxbuster)")
+@click.option("--wordlist-keyword", multiple=True, help="ffuf only: extra wordlist as PATH:KEYWORD (repeatable)")
+@click.option("--request-file", default="",
+              help="ffuf only: raw HTTP request with a FUZZ marker, used instead of the built URL and headers")
+@click.option("--replay-proxy", default="", help="ffuf only: send matched requests again through this proxy (e.g. Burp)")
+@click.option("--ferox-arg", "ferox_args", multiple=True,
+              help="Extra argument(s) appended to the feroxbuster command (repeatable)")
+@click.option("--ffuf-arg", "ffuf_args", multiple=True,
+              help="Extra argument(s) appended to the ffuf command (repeatable)")
+@click.option("--ffuf-mode", default="clusterbomb", type=click.Choice(["clusterbomb", "pitchfork"]),
+              help="How ffuf combines multiple wordlist keywords")
+def dir(tool, url, auto_scheme, headers, headers_file, random_agent, hmac_key, hmac_header,
+        summary_only, interactive_filter, display_rows, depth, max_output_lines, stop_on_first,
+        status_codes, filter_codes, min_status, max_status, filter_size, auto_filter,
+        smart_extensions, use_robots, smart_wordlist, resume, capture, capture_bytes,
+        exclude_url_regex, scope_regex, filter_redirect_loops, hash_bodies, scan_secrets,
+        keep_raw, http2, wordlist_keyword, request_file, replay_proxy, ferox_args, ffuf_args,
+        ffuf_mode, **common):
     """Directory and file brute-forcing mode."""
+    _check_flags()
+    _claim_stdout(common)
+    gate = _findings_gate(common)
+    known, tags = _load_baseline(common)
+    _check_rate_threads(common)
     available = check_tools()
     if not available.get(tool, False):
-        console.print(f"[red]Error: {tool} is not installed.[/red]")
-        console.print(f"[dim]Install with: sudo apt install {tool}[/dim]")
-        sys.exit(1)
+        _exit_tool_missing(tool)
 
-    options = {
-        "threads": str(threads),
-        "rate_limit": str(rate),
-        "proxy": proxy,
-        "extensions": extensions,
+    options = _shared_options(common)
+    options.update({
+        "extensions": _resolve_extensions(common),
         "depth": str(depth),
+        "max_output_lines": str(_line_cap(depth, max_output_lines)),
         "status_codes": status_codes,
         "filter_codes": filter_codes,
+        **_status_range(min_status, max_status),
         "filter_size": filter_size,
-    }
+        "resume": str(resume).lower(),
+        "capture": str(capture).lower(),
+        "stop_on_first": str(stop_on_first).lower(),
+        "capture_bytes": str(capture_bytes),
+        "scan_secrets": str(scan_secrets).lower(),
+        "hash_bodies": str(hash_bodies).lower(),
+        "filter_redirect_loops": str(filter_redirect_loops).lower(),
+        "keep_raw": str(keep_raw).lower(),
+        "http2": str(http2).lower(),
+        "wordlist_keywords": _keyword_wordlists(wordlist_keyword, tool),
+        "request_file": _request_file(request_file, tool),
+        "replay_proxy": _replay_proxy(replay_proxy, tool),
+        "extra_args": _extra_args(tool, ffuf_args, ferox_args),
+        "ffuf_mode": ffuf_mode,
+        "headers": _resolve_headers(headers, headers_file, random_agent),
+    })
+
+    exclude_url = _compile_patterns(exclude_url_regex, "--exclude-url-regex")
+    scope = _compile_patterns((scope_regex,), "--scope-regex")[0] if scope_regex else None
+    url = _with_scheme(url, auto_scheme, options)
+    _add_signed_header(options, hmac_key, hmac_header, url)
+    if http2:
+        _check_http2(tool, [url])
+    if scope and not scope.search(url):
+        console.print(f"[red]Error: --scope-regex does not match the target {url}.[/red]")
+        sys.exit(EXIT_USAGE)
 
-    asyncio.run(run_cli_scan("directory", tool, url, wordlist, options))
+    if resume and tool != "feroxbuster":
+        console.print("[red]Error: --resume is only supported with feroxbuster.[/red]")
+        sys.exit(EXIT_USAGE)
+
+    technologies: list[str] | None = None
+    if smart_extensions or smart_wordlist:
+        try:
+            technologies, detected = detect_extensions(url, new_http_client(options))
+        except OSError as exc:
+            logger.warning("technology detection failed: %s", exc)
+    if smart_extensions and technologies is not None:
+        if detected:
+            options["extensions"] = normalise_extensions(options["extensions"], ",".join(detected))
+            console.print(
+                f"[dim]Detected {', '.join(technologies)}, "
+                f"extensions: {options['extensions']}[/dim]"
+            )
+        else:
+            console.print("[dim]No known technology detected, extensions unchanged[/dim]")
+    if smart_wordlist:
+        common["wordlist"] = (_smart_wordlist(technologies or []),)
+
+    baseline = None
+    if auto_filter:
+        try:
+            baseline = probe_baseline(url, new_http_client(options))
+        except (OSError, ValueError) as exc:
+            logger.warning("baseline probe failed, auto-filter disabled: %s", exc)
+        else:
+            console.print(
+                f"[dim]Soft-404 baseline: {baseline.status_code} "
+                f"({baseline.size} bytes, {baseline.words} words)[/dim]"
+            )
+            if tool == "feroxbuster":
+                options["filter_similar_to"] = baseline.url
+
+    seeds = _robots_seeds(url, options) if use_robots else None
+    output_dir = _output_dir_template(common)
+    wordlist, cleanup = _prepare_wordlist(common, options, seeds)
+    try:
+        result = asyncio.run(run_cli_scan("directory", tool, url, wordlist, options, _scan_settings(
+            baseline=baseline, exclude_url=exclude_url, scope=scope, known=known, tags=tags,
+            output_dir=output_dir, summary_only=summary_only, legacy_json=common["legacy_json"],
+        )))
+    finally:
+        cleanup()
+    if interactive_filter:
+        _interactive_filter([result], display_rows)
+    _prune_old_runs(output_dir, [url], common["keep_runs"])
+    _write_metrics_file(common["metrics_file"], [result])
+    _write_sqlite_file(common["sqlite_file"], [result])
+    _write_stdout(common, [result])
+    _exit_for_results([result], common["fail_on_empty"], gate)
 
 
 @cli.command()
@@ -224,26 +1173,139 @@ def dir(tool, url, wordlist, threads, rate, proxy, extensions, output_dir,
 @click.option("--target", required=True, help="Target URL or IP")
 @click.option("--domain", required=True, help="Base domain for Host header")
 @_common_options
+@_request_options
+@_display_options
+@click.option("--hmac-key", default="", envvar="KRAKENBUSTER_HMAC_KEY",
+              help="Send an HMAC-SHA256 of the target URL under this key as a header")
+@click.option("--hmac-header", default="X-Signature", help="Header name for the --hmac-key signature")
 @click.option("--filter-codes", default="", help="Status codes to filter out")
 @click.option("--filter-size", default="", help="Filter response size")
-def vhost(tool, target, domain, wordlist, threads, rate, proxy, extensions,
-          output_dir, filter_codes, filter_size):
+@click.option("--vhost-match-status", default="", help="Only keep findings with these status codes (comma-separated)")
+@click.option("--min-status", default=0, type=click.IntRange(min=0, max=599),
+              help="Only keep findings with at least this status code")
+@click.option("--max-status", default=0, type=click.IntRange(min=0, max=599),
+              help="Only keep findings with at most this status code")
+@click.option("--collapse-duplicates", is_flag=True, help="Keep one vhost per group of identical responses")
+@click.option("--stop-on-first", is_flag=True, help="Stop the scan at the first finding and report only that one")
+@click.option("--auto-filter", is_flag=True, help="Probe a random Host and filter wildcard vhost responses")
+@click.option("--vhost-recurse", is_flag=True, help="ffuf only: fuzz FUZZ.<found vhost> for each vhost found")
+@click.option("--vhost-recurse-depth", default=2, type=click.IntRange(min=1),
+              help="How many levels of nested vhosts --vhost-recurse explores")
+@click.option("--keep-raw", is_flag=True, help="Keep ffuf's own JSON output in <output-dir>/raw/")
+@click.option("--wordlist-keyword", multiple=True, help="ffuf only: extra wordlist as PATH:KEYWORD (repeatable)")
+@click.option("--request-file", default="",
+              help="ffuf only: raw HTTP request with a FUZZ marker, used instead of the built URL and headers")
+@click.option("--replay-proxy", default="", help="ffuf only: send matched requests again through this proxy (e.g. Burp)")
+@click.option("--ffuf-arg", "ffuf_args", multiple=True,
+              help="Extra argument(s) appended to the ffuf command (repeatable)")
+@click.option("--ffuf-mode", default="clusterbomb", type=click.Choice(["clusterbomb", "pitchfork"]),
+              help="How ffuf combines multiple wordlist keywords")
+def vhost(tool, target, domain, auto_scheme, headers, headers_file, random_agent, hmac_key,
+          hmac_header, summary_only, interactive_filter, display_rows, filter_codes, filter_size,
+          vhost_match_status, min_status, max_status, collapse_duplicates, stop_on_first,
+          auto_filter, vhost_recurse, vhost_recurse_depth, keep_raw, http2, wordlist_keyword,
+          request_file, replay_proxy, ffuf_args, ffuf_mode, **common):
     """Virtual host fuzzing mode."""
+    _check_flags()
+    _claim_stdout(common)
+    gate = _findings_gate(common)
+    known, tags = _load_baseline(common)
+    _check_rate_threads(common)
     available = check_tools()
     if not available.get(tool, False):
-        console.print(f"[red]Error: {tool} is not installed.[/red]")
-        sys.exit(1)
+        _exit_tool_missing(tool)
 
-    options = {
-        "threads": str(threads),
-        "rate_limit": str(rate),
-        "proxy": proxy,
+    try:
+        match_status = parse_status_codes(vhost_match_status)
+    except ValueError as exc:
+        console.print(f"[red]Error: --vhost-match-status: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+
+    if vhost_recurse and tool != "ffuf":
+        console.print("[red]Error: --vhost-recurse is only supported with ffuf.[/red]")
+        sys.exit(EXIT_USAGE)
+
+    options = _shared_options(common)
+    options.update({
         "domain": domain,
+        "stop_on_first": str(stop_on_first).lower(),
         "filter_codes": filter_codes,
+        **_status_range(min_status, max_status),
         "filter_size": filter_size,
-    }
+        "keep_raw": str(keep_raw).lower(),
+        "http2": str(http2).lower(),
+        "wordlist_keywords": _keyword_wordlists(wordlist_keyword, tool),
+        "request_file": _request_file(request_file, tool),
+        "replay_proxy": _replay_proxy(replay_proxy, tool),
+        "extra_args": _extra_args(tool, ffuf_args, ()),
+        "ffuf_mode": ffuf_mode,
+        "headers": _resolve_headers(headers, headers_file, random_agent),
+    })
+    if auto_scheme or "://" in target:
+        target = _with_scheme(target, auto_scheme, options)
+    else:
+        # Vhost scans often aim at a bare IP:port, where probing is no help,
+        # so the scheme is taken from the port instead
+        try:
+            target = default_scheme(target)
+        except ValueError as exc:
+            console.print(f"[red]Error: --target: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        console.print(f"[dim]No scheme given, using {target}[/dim]")
+    _add_signed_header(options, hmac_key, hmac_header, target)
+    if request_file:
+        _check_request_host(options["request_file"], domain)
+    if http2:
+        _check_http2(tool, [target])
+    vhost_warning = check_vhost_config(target, domain)
+    if vhost_warning:
+        console.print(Panel(
+            vhost_warning, title="Check --target and --domain", border_style="yellow", expand=False
+        ))
 
-    asyncio.run(run_cli_scan("vhost", tool, target, wordlist, options))
+    baseline = None
+    if auto_filter:
+        try:
+            baseline = probe_vhost_baseline(target, domain, new_http_client(options))
+        except (OSError, ValueError) as exc:
+            logger.warning("vhost baseline probe failed, auto-filter disabled: %s", exc)
+        else:
+            console.print(
+                f"[dim]Wildcard vhost baseline ({baseline.url}): {baseline.status_code} "
+                f"({baseline.size} bytes, {baseline.words} words)[/dim]"
+            )
+            if tool == "ffuf":
+                options["filter_size"] = ",".join(filter(None, [filter_size, str(baseline.size)]))
+                options["filter_words"] = str(baseline.words)
+
+    output_dir = _output_dir_template(common)
+    wordlist, cleanup = _prepare_wordlist(common, options)
+    scan = _scan_settings(
+        baseline=baseline, match_status=match_status, known=known, tags=tags,
+        output_dir=output_dir, summary_only=summary_only, legacy_json=common["legacy_json"],
+        collapse_duplicates=collapse_duplicates,
+    )
+    try:
+        if vhost_recurse:
+            nested = asyncio.run(run_vhost_recursive(
+                tool, target, domain, wordlist, options, vhost_recurse_depth, scan
+            ))
+            results = [scan for _, scan in nested]
+            print_scan_summary(console, merge_scan_results(results))
+            for parent, scan in nested[1:]:
+                if scan._json_path:
+                    console.print(f"[dim]JSON output ({parent}):[/dim] {scan._json_path}")
+        else:
+            results = [asyncio.run(run_cli_scan("vhost", tool, target, wordlist, options, scan))]
+    finally:
+        cleanup()
+    if interactive_filter:
+        _interactive_filter(results, display_rows)
+    _prune_old_runs(output_dir, [target], common["keep_runs"])
+    _write_metrics_file(common["metrics_file"], results)
+    _write_sqlite_file(common["sqlite_file"], results)
+    _write_stdout(common, results)
+    _exit_for_results(results, common["fail_on_empty"], gate)
 
 
 @cli.command()
@@ -252,31 +1314,390 @@ def vhost(tool, target, domain, wordlist, threads, rate, proxy, extensions,
 @_common_options
 @click.option("--resolver", default="", help="Custom DNS resolver")
 @click.option("--show-ips/--no-show-ips", default=True, help="Show resolved IPs")
-def dns(tool, domain, wordlist, threads, rate, proxy, extensions, output_dir,
-        resolver, show_ips):
+def dns(tool, domain, resolver, show_ips, **common):
     """DNS subdomain enumeration mode."""
+    _check_flags()
+    _claim_stdout(common)
+    gate = _findings_gate(common)
+    known, tags = _load_baseline(common)
     available = check_tools()
     if not available.get(tool, False):
-        console.print(f"[red]Error: {tool} is not installed.[/red]")
-        sys.exit(1)
+        _exit_tool_missing(tool)
 
+    shared = _shared_options(common)
     options = {
-        "threads": str(threads),
+        "threads": str(common["threads"]),
         "resolver": resolver,
         "show_ips": str(show_ips).lower(),
     }
+    for key in ("skip_empty", "compress", "compact_json"):
+        options[key] = shared[key]
 
-    asyncio.run(run_cli_scan("dns", tool, domain, wordlist, options))
+    output_dir = _output_dir_template(common)
+    wordlist, cleanup = _prepare_wordlist(common, shared)
+    try:
+        result = asyncio.run(run_cli_scan("dns", tool, domain, wordlist, options, _scan_settings(
+            known=known, tags=tags, output_dir=output_dir, legacy_json=common["legacy_json"],
+        )))
+    finally:
+        cleanup()
+    _prune_old_runs(output_dir, [domain], common["keep_runs"])
+    _write_metrics_file(common["metrics_file"], [result])
+    _write_sqlite_file(common["sqlite_file"], [result])
+    _write_stdout(common, [result])
+    _exit_for_results([result], common["fail_on_empty"], gate)
+
+
+def load_target_specs(path: str) -> list[TargetSpec]:
+    """Read combined-mode targets from a plain or JSON Lines file.
+
+    Plain files hold one URL per line. A .jsonl file, or one whose first
+    entry starts with "{", holds one {"url", "domain", "wordlist"} object per
+    line, where only "url" is required. Blank lines and # comments are
+    skipped in both. Raises OSError if the file cannot be read and ValueError
+    for a malformed JSON line.
+    """
+    with open(path, "r", errors="ignore") as fh:
+        entries = [
+            (number, line.strip())
+            for number, line in enumerate(fh, 1)
+            if line.strip() and not line.strip().startswith("#")
+        ]
+
+    jsonl = path.endswith(".jsonl") or (bool(entries) and entries[0][1].startswith("{"))
+    if not jsonl:
+        return [TargetSpec(url=line) for _, line in entries]
+
+    specs: list[TargetSpec] = []
+    for number, line in entries:
+        try:
+            data = json.loads(line)
+        except ValueError as exc:
+            raise ValueError(f"line {number}: invalid JSON: {exc}") from None
+        if not isinstance(data, dict) or not isinstance(data.get("url"), str) or not data["url"]:
+            raise ValueError(f"line {number}: expected an object with a \"url\" string")
+        specs.append(TargetSpec(
+            url=data["url"],
+            domain=str(data.get("domain") or ""),
+            wordlist=str(data.get("wordlist") or ""),
+        ))
+    return specs
+
+
+def write_batch_summary(results: list[HostResult], output_dir: str) -> Path:
+    """Write the combined-run roll-up as batch_summary_<timestamp>.json.
+
+    Hosts are sorted by total findings, most first, matching the table.
+    """
+    def scan_entry(scan: ScanResult | None, error: str) -> dict:
+        return {
+            "findings": len(scan.findings) if scan else None,
+            "error": error,
+            "output": str(scan._json_path) if scan and scan._json_path else "",
+        }
+
+    hosts = [
+        {
+            "host": r.host,
+            "dir": scan_entry(r.dir_result, r.dir_error),
+            "vhost": scan_entry(r.vhost_result, r.vhost_error),
+            "duration_seconds": round(r.duration_seconds, 3),
+            "stderr_lines": sum(
+                len(scan.stderr_lines) for scan in (r.dir_result, r.vhost_result) if scan
+            ),
+        }
+        for r in sorted(results, key=lambda r: (-r.total_findings, r.host))
+    ]
+    base = Path(output_dir)
+    base.mkdir(parents=True, exist_ok=True)
+    path = unique_path(base / f"batch_summary_{datetime.now().strftime('%Y%m%d_%H%M%S')}", ".json")
+    path.write_text(json.dumps({"generated_at": utc_timestamp(), "hosts": hosts}, indent=2))
+    return path
+
+
+def _print_combined_summary(results: list[HostResult]) -> None:
+    """Print the roll-up table and output files for a combined run."""
+    # Only show columns for scan modes that ran for at least one host, so a
+    # run with a missing tool does not report a column of zeros.
+    kinds = [
+        kind for kind in ("dir", "vhost")
+        if any(
+            getattr(r, f"{kind}_result") or getattr(r, f"{kind}_error")
+            for r in results
+        )
+    ]
+
+    # Busiest hosts first, so the interesting part of a large batch is on top
+    results = sorted(results, key=lambda r: (-r.total_findings, r.host))
+
+    table = Table(title="Combined Scan Summary")
+    table.add_column("Host", style="cyan")
+    for kind in kinds:
+        total = sum(
+            len(scan.findings) for r in results if (scan := getattr(r, f"{kind}_result"))
+        )
+        table.add_column(f"{kind.capitalize()} findings ({total})", style="green", justify="right")
+    table.add_column("Duration", justify="right")
+    table.add_column("Stderr lines", style="red", justify="right")
+
+    def cell(scan: ScanResult | None, error: str) -> str:
+        if error:
+            return "error"
+        if scan is None:
+            return "skipped"
+        return str(len(scan.findings))
+
+    for r in results:
+        scans = [scan for scan in (r.dir_result, r.vhost_result) if scan]
+        minutes, seconds = divmod(int(r.duration_seconds), 60)
+        table.add_row(
+            r.host,
+            *(cell(getattr(r, f"{kind}_result"), getattr(r, f"{kind}_error")) for kind in kinds),
+            f"{minutes}m {seconds}s",
+            str(sum(len(scan.stderr_lines) for scan in scans)),
+        )
+
+    console.print()
+    console.print(table)
+
+    console.print("\n[bold]Output files:[/bold]")
+    for r in results:
+        for scan in (r.dir_result, r.vhost_result):
+            if scan:
+                written = scan._json_path or "nothing written (no findings)"
+                console.print(f"  [dim]{r.host} {scan.mode}:[/dim] {written}")
+        for kind, error in (("dir", r.dir_error), ("vhost", r.vhost_error)):
+            if error:
+                console.print(f"  [red]{r.host} {kind} failed: {error}[/red]")
+        if r.vhost_result:
+            for cluster in r.vhost_result.clusters:
+                console.print(f"  [yellow]{r.host}: {cluster.describe()}[/yellow]")
+
+
+def _only_tools(
+    only: str | None, dir_tool: str | None, vhost_tool: str | None
+) -> tuple[str | None, str | None]:
+    """Return combined's (dir, vhost) tools for --only, None for a skipped scan.
+
+    The skipped scan's tool is dropped rather than run, even if given; the
+    tool of each scan that runs is required.
+    """
+    for kind, tool in (("dir", dir_tool), ("vhost", vhost_tool)):
+        if only in (None, kind) and not tool:
+            console.print(f"[red]Error: --{kind}-tool is required.[/red]")
+            sys.exit(EXIT_USAGE)
+    if only == "dir":
+        return dir_tool, None
+    if only == "vhost":
+        return None, vhost_tool
+    return dir_tool, vhost_tool
+
+
+@cli.command()
+@click.option("--dir-tool", default=None, type=click.Choice(DIR_TOOLS), help="Tool for directory scanning")
+@click.option("--vhost-tool", default=None, type=click.Choice(VHOST_TOOLS), help="Tool for vhost fuzzing")
+@click.option("--only", default=None, type=click.Choice(["dir", "vhost"]),
+              help="Run only the directory or vhost scan, keeping the combined output")
+@click.option("--url", default="", help="Target URL")
+@click.option("--target-list", default="", help="File of target URLs, one per line, or .jsonl target specs")
+@click.option("--target-cidr", default="", help="Scan every host address in a network such as 10.0.0.0/24")
+@click.option("--cidr-scheme", default="http", type=click.Choice(["http", "https"]),
+              help="Scheme for --target-cidr hosts")
+@click.option("--cidr-port", default=0, type=click.IntRange(min=0, max=65535),
+              help="Port for --target-cidr hosts (0 for the scheme's default)")
+@click.option("--domain", default="", help="Base domain for vhost (defaults to each target's hostname)")
+@_common_options
+@_extension_options
+@_request_options
+@_display_options
+@click.option("--vhost-wordlist", default="", help="Wordlist for vhost fuzzing (defaults to --wordlist)")
+@click.option("--depth", default=3, type=click.IntRange(min=0),
+              help="Recursion depth for directory scan (0 for unlimited)")
+@click.option("--max-output-lines", default=0, type=click.IntRange(min=0),
+              help="Stop each directory scan after its tool prints this many lines, not requests (0 for no cap)")
+@click.option("--concurrency", default=1, type=click.IntRange(min=1), help="Number of hosts to scan in parallel")
+@click.option("--target-delay", default="0", help="Pause after each host before starting the next (e.g. 500ms, 5s, 1m)")
+@click.option("--keep-raw", is_flag=True,
+              help="Keep the tools' own JSON output in <output-dir>/raw/ (ffuf, feroxbuster)")
+@click.option("--collapse-duplicates", is_flag=True, help="Keep one vhost per group of identical responses")
+def combined(dir_tool, vhost_tool, only, url, target_list, target_cidr, cidr_scheme, cidr_port,
+             domain, auto_scheme, headers, headers_file, random_agent, summary_only,
+             interactive_filter, display_rows, vhost_wordlist, depth, max_output_lines, concurrency,
+             target_delay, keep_raw, collapse_duplicates, http2, **common):
+    """Directory and vhost scanning in parallel, for one or many hosts."""
+    _check_flags()
+    _claim_stdout(common)
+    gate = _findings_gate(common)
+    known, tags = _load_baseline(common)
+    _check_rate_threads(common)
+    dir_tool, vhost_tool = _only_tools(only, dir_tool, vhost_tool)
+
+    if not (url or target_list or target_cidr):
+        console.print("[red]Error: give one of --url, --target-list or --target-cidr.[/red]")
+        sys.exit(EXIT_USAGE)
+
+    try:
+        delay = parse_duration(target_delay)
+    except ValueError as exc:
+        console.print(f"[red]Error: --target-delay: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+
+    if target_list:
+        try:
+            targets = load_target_specs(target_list)
+        except (OSError, ValueError) as exc:
+            console.print(f"[red]Error: cannot read target list: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        if not targets:
+            console.print("[red]Error: target list is empty.[/red]")
+            sys.exit(EXIT_USAGE)
+    elif target_cidr:
+        try:
+            targets = [TargetSpec(url=u) for u in expand_cidr(target_cidr, cidr_scheme, cidr_port)]
+        except ValueError as exc:
+            console.print(f"[red]Error: --target-cidr: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        console.print(f"[dim]Expanded {target_cidr} to {len(targets)} hosts[/dim]")
+    else:
+        targets = [TargetSpec(url=url)]
+
+    for spec in targets:
+        if not spec.wordlist:
+            continue
+        try:
+            check_wordlist_path(spec.wordlist)
+        except (OSError, ValueError) as exc:
+            console.print(f"[red]Error: cannot read wordlist for {spec.url}: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+
+    available = check_tools()
+    if only and not available.get(dir_tool or vhost_tool, False):
+        _exit_tool_missing(dir_tool or vhost_tool)
+    if dir_tool and not available.get(dir_tool, False):
+        logger.warning(
+            "%s is not installed (install with: %s), skipping directory scans.",
+            dir_tool, install_hint(dir_tool),
+        )
+        dir_tool = None
+    if vhost_tool and not available.get(vhost_tool, False):
+        logger.warning(
+            "%s is not installed (install with: %s), skipping vhost scans.",
+            vhost_tool, install_hint(vhost_tool),
+        )
+        vhost_tool = None
+    if not dir_tool and not vhost_tool:
+        console.print("[red]Error: neither scan tool is installed.[/red]")
+        sys.exit(EXIT_TOOL_MISSING)
+
+    shared = _shared_options(common)
+    shared["keep_raw"] = str(keep_raw).lower()
+    shared["http2"] = str(http2).lower()
+    shared["headers"] = _resolve_headers(headers, headers_file, random_agent)
+    for spec in targets:
+        spec.url = _with_scheme(spec.url, auto_scheme, shared)
+    if http2:
+        for tool in dict.fromkeys(filter(None, (dir_tool, vhost_tool))):
+            _check_http2(tool, [spec.url for spec in targets])
+    dir_options = dict(
+        shared,
+        extensions=_resolve_extensions(common),
+        depth=str(depth),
+        max_output_lines=str(_line_cap(depth, max_output_lines)),
+        extra_args=_extra_args(dir_tool, (), ()) if dir_tool else "",
+    )
+    vhost_options = dict(shared, extra_args=_extra_args(vhost_tool, (), ()) if vhost_tool else "")
+
+    output_dir = _output_dir_template(common)
+
+    # The shared wordlist is optional when every target brings its own
+    if common["wordlist"] or common["wordlist_url"] or not all(spec.wordlist for spec in targets):
+        wordlist, cleanup = _prepare_wordlist(common, shared)
+    else:
+        wordlist, cleanup = "", lambda: None
+    try:
+        results = asyncio.run(scan_targets_async(
+            targets, wordlist, dir_tool=dir_tool, vhost_tool=vhost_tool, domain=domain,
+            vhost_wordlist=vhost_wordlist, dir_options=dir_options, vhost_options=vhost_options,
+            concurrency=concurrency, target_delay=delay, settings=_scan_settings(
+                known=known, tags=tags, output_dir=output_dir, summary_only=summary_only,
+                legacy_json=common["legacy_json"], collapse_duplicates=collapse_duplicates,
+            ),
+        ))
+    finally:
+        cleanup()
+
+    batch = bool(target_list or target_cidr)
+    _print_combined_summary(results)
+    if batch:
+        try:
+            path = write_batch_summary(
+                results, str(expand_output_dir(output_dir, "batch", settings.run_timestamp))
+            )
+        except OSError as exc:
+            logger.warning("cannot write batch summary: %s", exc)
+        else:
+            console.print(f"\n[dim]Batch summary:[/dim] {path}")
+    # "batch" matches the {host} used for the batch summary directory
+    _prune_old_runs(
+        output_dir,
+        [spec.url for spec in targets] + (["batch"] if batch else []),
+        common["keep_runs"],
+    )
+    scans = [scan for r in results for scan in (r.dir_result, r.vhost_result) if scan]
+    if interactive_filter:
+        _interactive_filter(scans, display_rows)
+    _write_metrics_file(common["metrics_file"], scans)
+    _write_sqlite_file(common["sqlite_file"], scans)
+    _write_stdout(common, scans)
+    _exit_for_results(
+        scans, common["fail_on_empty"], gate,
+        errored=any(r.dir_error or r.vhost_error for r in results),
+    )
+
+
+@cli.command()
+@click.argument("files", nargs=-1, required=True)
+@click.option("--output", "-o", "output", default="",
+              help="Merged JSON file (default merged_<timestamp>.json in the output directory)")
+def merge(files, output):
+    """Merge findings JSON files into one deduplicated, sorted file."""
+    try:
+        findings, duplicates = merge_finding_files(list(files))
+    except (OSError, ValueError) as exc:
+        console.print(f"[red]Error: cannot read findings file {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+
+    if output:
+        json_path = Path(output)
+    else:
+        base = expand_output_dir(
+            load_config().get("general", "output_directory", fallback="./output"),
+            "merged", settings.run_timestamp,
+        )
+        try:
+            check_writable(base)
+        except OSError as exc:
+            console.print(f"[red]Error: {exc}[/red]")
+            sys.exit(EXIT_USAGE)
+        json_path = unique_path(base / f"merged_{settings.run_timestamp}", ".json")
+    try:
+        text_path = write_merged(json_path, findings, list(files))
+    except OSError as exc:
+        console.print(f"[red]Error: cannot write merged findings: {exc}[/red]")
+        sys.exit(EXIT_USAGE)
+
+    console.print(
+        f"Merged {len(files)} files: {len(findings):,} findings, "
+        f"{duplicates:,} duplicates collapsed"
+    )
+    console.print(f"[dim]JSON:[/dim] {json_path}")
+    console.print(f"[dim]Text:[/dim] {text_path}")
 
 
 @cli.command(name="__main__", hidden=True)
 def main_entry():
     """Support python -m krakenbuster."""
-    from krakenbuster.app import KrakenBusterApp
-    app = KrakenBusterApp()
-    result = app.run()
-    if result and isinstance(result, list):
-        _execute_commands(result)
+    _run_tui()
 
 
 if __name__ == "__main__":
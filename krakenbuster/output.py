@@ -2,14 +2,64 @@
 
 from __future__ import annotations
 
+import gzip
+import hashlib
 import json
 import re
-from dataclasses import dataclass, field, asdict
-from datetime import datetime
+import shutil
+import sqlite3
+import statistics
+import tempfile
+import threading
+import time
+import zlib
+from contextlib import closing
+from concurrent.futures import ThreadPoolExecutor
+from dataclasses import dataclass, field, fields, asdict
+from datetime import datetime, timezone
 from pathlib import Path
+from typing import TYPE_CHECKING, Iterable
+from urllib.parse import urldefrag, urljoin, urlparse, urlsplit
 
 import aiofiles
 
+if TYPE_CHECKING:
+    from krakenbuster.probe import HttpClient
+
+from krakenbuster import __version__
+
+# Version of the JSON envelope written by write_envelope()
+SCHEMA_VERSION = 1
+
+# A finding's size is flagged when it is this many standard deviations from
+# the median of its status class, given at least ANOMALY_MIN_GROUP findings.
+ANOMALY_SIGMA = 2.0
+ANOMALY_MIN_GROUP = 3
+
+# Triage tags an operator can give findings at the --interactive-filter prompt
+TAGS = ("interesting", "ignore", "reviewed")
+
+# Number of captured findings listed by slowest_findings() in the summary
+SLOWEST_SHOWN = 5
+
+# Vhost findings sharing one response signature at least this many times are
+# reported as a cluster, as they most likely all hit the default site.
+VHOST_CLUSTER_MIN = 3
+
+# Path keywords whose findings are always worth a look, whatever the status
+DEFAULT_INTERESTING_KEYWORDS = ("admin", ".git", "backup", "config", ".env", "api")
+
+# Largest body read per URL by hash_and_group(), so one huge file cannot stall it
+HASH_MAX_BYTES = 10 * 1024 * 1024
+
+# Widest URL shown in the TUI's findings tables before truncate_url() shortens it
+URL_DISPLAY_MAX = 58
+
+# Marker file identifying a per-run output directory made from {timestamp}
+RUN_MARKER = ".krakenbuster_run"
+RUN_TIMESTAMP_FORMAT = "%Y%m%d_%H%M%S"
+_RUN_DIR_NAME = re.compile(r"\d{8}_\d{6}")
+
 
 @dataclass
 class Finding:
@@ -21,6 +71,122 @@ class Finding:
     words: int = 0
     lines: int = 0
     redirect: str = ""
+    found_at: str = ""  # RFC 3339 UTC timestamp of when the finding was parsed
+    capture: str = ""  # path of the captured response, if --capture was used
+    inputs: dict[str, str] = field(default_factory=dict)  # ffuf keyword values, e.g. {"W1": "admin"}
+    vhost_chain: list[str] = field(default_factory=list)  # parent vhosts under --vhost-recurse
+    body_hash: str = ""  # SHA-256 of the response body, if --hash-bodies was used
+    encoding: str = ""  # Content-Encoding of the captured response, if --capture was used
+    latency_ms: int = 0  # time taken to fetch the captured response, if --capture was used
+    tag: str = ""  # triage tag from TAGS, set at the --interactive-filter prompt or carried from --baseline
+
+
+@dataclass
+class VhostCluster:
+    """Vhost findings that all returned the same status, size and word count."""
+
+    status_code: int = 0
+    size: int = 0
+    words: int = 0
+    findings: list[Finding] = field(default_factory=list)
+
+    def describe(self) -> str:
+        return (
+            f"{len(self.findings)} vhosts returned identical "
+            f"{self.status_code}/{self.size}b responses - likely wildcard"
+        )
+
+
+@dataclass
+class SecretMatch:
+    """A likely secret found in a captured response."""
+
+    kind: str = ""
+    value: str = ""
+    url: str = ""
+
+
+# Small, high-signal patterns; each must be distinctive enough on its own
+# that a match is worth a human look.
+SECRET_PATTERNS: list[tuple[str, re.Pattern[bytes]]] = [
+    ("aws_access_key_id", re.compile(rb"\b(?:AKIA|ASIA)[0-9A-Z]{16}\b")),
+    ("jwt", re.compile(rb"\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}")),
+    ("github_token", re.compile(rb"\bgh[pousr]_[A-Za-z0-9]{36}\b")),
+    ("google_api_key", re.compile(rb"\bAIza[0-9A-Za-z_-]{35}\b")),
+    ("slack_token", re.compile(rb"\bxox[abprs]-[A-Za-z0-9-]{10,}")),
+    ("stripe_secret_key", re.compile(rb"\bsk_live_[0-9A-Za-z]{24,}\b")),
+    ("private_key", re.compile(rb"-----BEGIN (?:RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----")),
+]
+
+
+@dataclass
+class ScanMeta:
+    """Metadata describing a scan, embedded in every output file."""
+
+    krakenbuster_version: str = __version__
+    tool: str = ""
+    tool_version: str = ""
+    mode: str = ""
+    target: str = ""
+    wordlist: str = ""
+    threads: str = ""
+    rate_limit: str = ""
+    proxy: str = ""
+    start_time: str = ""
+    end_time: str = ""
+
+    @classmethod
+    def from_options(
+        cls,
+        tool: str,
+        mode: str,
+        target: str,
+        wordlist: str,
+        options: dict[str, str],
+        tool_version: str = "",
+    ) -> ScanMeta:
+        """Build metadata for a scan starting now, masking proxy credentials."""
+        return cls(
+            tool=tool,
+            tool_version=tool_version,
+            mode=mode,
+            target=target,
+            wordlist=wordlist,
+            threads=options.get("threads", ""),
+            rate_limit=options.get("rate_limit", ""),
+            proxy=mask_credentials(options.get("proxy", "")),
+            start_time=utc_timestamp(),
+        )
+
+    def header_lines(self) -> list[str]:
+        """Return the metadata as '# key: value' lines for text outputs."""
+        return [f"# {key}: {value}" for key, value in asdict(self).items() if value]
+
+
+class FindingStore:
+    """Findings collected while a scan runs, safe to share between threads.
+
+    The output readers add findings as they arrive while progress views
+    count them and threaded passes take snapshots; every access holds the
+    store's lock, so a snapshot is never taken mid-append.
+    """
+
+    def __init__(self, findings: Iterable[Finding] = ()) -> None:
+        self._lock = threading.Lock()
+        self._findings = list(findings)
+
+    def add(self, finding: Finding) -> None:
+        with self._lock:
+            self._findings.append(finding)
+
+    def snapshot(self) -> list[Finding]:
+        """Return a copy of the findings so far, in arrival order."""
+        with self._lock:
+            return list(self._findings)
+
+    def __len__(self) -> int:
+        with self._lock:
+            return len(self._findings)
 
 
 @dataclass
@@ -36,7 +202,12 @@ class ScanResult:
     findings: list[Finding] = field(default_factory=list)
     raw_lines: list[str] = field(default_factory=list)
     stderr_lines: list[str] = field(default_factory=list)
+    secrets: list[SecretMatch] = field(default_factory=list)
+    clusters: list[VhostCluster] = field(default_factory=list)  # vhost mode only
+    # Body hash -> URLs serving that identical body, from --hash-bodies
+    duplicate_bodies: dict[str, list[str]] = field(default_factory=dict)
     errors: int = 0
+    failed: bool = False  # the tool exited with an error status
 
     @property
     def duration_formatted(self) -> str:
@@ -52,41 +223,949 @@ class ScanResult:
         return grouped
 
 
+def utc_timestamp() -> str:
+    """Return the current UTC time as an RFC 3339 timestamp."""
+    return datetime.now(timezone.utc).isoformat(timespec="seconds")
+
+
+def mask_credentials(url: str) -> str:
+    """Replace the password in a URL's userinfo (user:pass@host) with ***."""
+    return re.sub(r"(://[^:/@\s]+:)[^@/\s]+@", r"\1***@", url)
+
+
+# Request headers whose values are masked when a command line is shown
+SENSITIVE_HEADERS = frozenset({
+    "authorization",
+    "proxy-authorization",
+    "cookie",
+    "x-api-key",
+    "x-auth-token",
+    "x-signature",
+})
+
+# Flags whose following value is masked when a command line is shown
+SENSITIVE_FLAGS = frozenset({"--hmac-key"})
+
+
+def mask_argv(argv: list[str]) -> list[str]:
+    """Mask URL passwords, sensitive header values and SENSITIVE_FLAGS values in a command line."""
+    masked = []
+    for i, arg in enumerate(argv):
+        name, colon, _ = arg.partition(":")
+        flag, equals, _ = arg.partition("=")
+        if colon and name.strip().lower() in SENSITIVE_HEADERS:
+            arg = f"{name}: ***"
+        elif equals and flag in SENSITIVE_FLAGS:
+            arg = f"{flag}=***"
+        elif i and argv[i - 1] in SENSITIVE_FLAGS:
+            arg = "***"
+        masked.append(mask_credentials(arg))
+    return masked
+
+
+def status_colour(code: int | None, overrides: dict[str, str] | None = None) -> str:
+    """Return a Rich colour for an HTTP status code.
+
+    Overrides map a status class ("2xx", "3xx", ...) to a colour and take
+    precedence over the default palette.
+    """
+    if code is None:
+        return "white"
+    if overrides:
+        custom = overrides.get(f"{code // 100}xx")
+        if custom:
+            return custom
+    if code == 200:
+        return "green"
+    elif code in (301, 302, 307):
+        return "yellow"
+    elif code in (401, 403):
+        return "cyan"
+    elif code >= 500:
+        return "red"
+    return "white"
+
+
+def truncate_url(url: str, max_len: int = URL_DISPLAY_MAX) -> str:
+    """Shorten a URL to max_len characters, keeping its host and final segment.
+
+    Middle path segments are replaced with "..." (as in
+    http://site/a/.../admin.php) so the filename stays visible. If even that
+    is too long, the start is cut instead, keeping the tail.
+    """
+    if len(url) <= max_len:
+        return url
+    if max_len <= 3:
+        return url[:max_len]
+
+    parts = urlsplit(url)
+    segments = parts.path.rstrip("/").split("/")
+    if parts.scheme and parts.netloc and len(segments) > 2:
+        head = f"{parts.scheme}://{parts.netloc}"
+        last = segments[-1] + ("/" if parts.path.endswith("/") else "")
+        if parts.query:
+            last += f"?{parts.query}"
+        lead: list[str] = []
+        shortened = f"{head}/.../{last}"
+        for segment in segments[1:-1]:
+            candidate = "/".join([head, *lead, segment, "...", last])
+            if len(candidate) > max_len:
+                break
+            lead.append(segment)
+            shortened = candidate
+        if len(shortened) <= max_len:
+            return shortened
+    return "..." + url[-(max_len - 3):]
+
+
+def _ipv6_filename(address: str) -> str:
+    """Spell an IPv6 address with "-" for its colons and dots.
+
+    A "::" at either end gets a 0 beside it (0::1 is the same address as
+    ::1), so the result never starts or ends with "-".
+    """
+    if address.startswith("::"):
+        address = "0" + address
+    if address.endswith("::"):
+        address += "0"
+    return re.sub(r"[:.]", "-", address)
+
+
 def sanitise_hostname(target: str) -> str:
-    """Sanitise a hostname for use in filenames."""
+    """Sanitise a hostname for use in filenames.
+
+    The colons of a bracketed IPv6 address become "-", so http://[::1]:8080/
+    gives "0--1_8080" rather than losing the address to underscores.
+    """
     cleaned = re.sub(r"https?://", "", target)
-    cleaned = re.sub(r"[^a-zA-Z0-9]", "_", cleaned)
+    cleaned = re.sub(
+        r"\[([0-9a-fA-F:.]+)\]|[^a-zA-Z0-9]",
+        lambda m: _ipv6_filename(m.group(1)) if m.group(1) else "_",
+        cleaned,
+    )
     cleaned = cleaned.strip("_")
     return cleaned
 
 
+def check_writable(directory: Path) -> None:
+    """Create directory if needed and check a file can be written in it.
+
+    A directory can exist yet be read-only, which would otherwise only show
+    when the first result is written. Raises OSError naming the directory.
+    """
+    try:
+        directory.mkdir(parents=True, exist_ok=True)
+        with tempfile.TemporaryFile(dir=directory):
+            pass
+    except OSError as exc:
+        raise OSError(f"output directory {directory} is not writable: {exc.strerror or exc}") from exc
+
+
 def generate_output_paths(
     target: str, tool: str, mode: str, output_dir: str = "./output"
 ) -> tuple[Path, Path]:
-    """Generate output file paths for raw and JSON output."""
+    """Generate output file paths for raw and JSON output.
+
+    Raises OSError if output_dir cannot be created or written to.
+    """
     base = Path(output_dir)
-    base.mkdir(parents=True, exist_ok=True)
+    check_writable(base)
 
     hostname = sanitise_hostname(target)
     timestamp = datetime.now().strftime("%Y%m%d_%H%M%S")
     prefix = f"{hostname}_{tool}_{mode}_{timestamp}"
 
-    raw_path = base / f"{prefix}.txt"
-    json_path = base / f"{prefix}.json"
+    raw_path = unique_path(base / prefix, ".txt")
+    json_path = raw_path.with_suffix(".json")
     return raw_path, json_path
 
 
+def expand_output_dir(template: str, host: str, timestamp: str = "") -> Path:
+    """Fill the {host} and {timestamp} tokens of an output directory and create it.
+
+    timestamp defaults to now. A directory whose last component is
+    {timestamp} is a per-run directory and gets a marker file, which
+    prune_runs() requires before deleting anything.
+    """
+    timestamp = timestamp or datetime.now().strftime(RUN_TIMESTAMP_FORMAT)
+    path = Path(template.replace("{host}", host).replace("{timestamp}", timestamp))
+    path.mkdir(parents=True, exist_ok=True)
+    if Path(template).name == "{timestamp}":
+        (path / RUN_MARKER).touch()
+    return path
+
+
+def prune_runs(base: Path, keep: int) -> list[Path]:
+    """Delete all but the newest `keep` run directories directly under base.
+
+    Only directories named like a run timestamp and holding the run marker
+    count, so anything else under base is left alone. Returns the removed
+    directories. Raises OSError if one cannot be removed.
+    """
+    runs = sorted(
+        path for path in base.iterdir()
+        if _RUN_DIR_NAME.fullmatch(path.name)
+        and path.is_dir()
+        and not path.is_symlink()
+        and (path / RUN_MARKER).is_file()
+    )
+    stale = runs[:-keep] if keep > 0 else runs
+    for path in stale:
+        shutil.rmtree(path)
+    return stale
+
+
+def unique_path(base: Path, ext: str) -> Path:
+    """Reserve a new file at base + ext, adding _1, _2, ... if it is taken.
+
+    The file is created exclusively so concurrent runs sharing an output
+    directory cannot both claim the same name within the same second.
+    """
+    candidate = base.with_name(base.name + ext)
+    counter = 0
+    while True:
+        try:
+            with open(candidate, "x"):
+                return candidate
+        except FileExistsError:
+            counter += 1
+            candidate = base.with_name(f"{base.name}_{counter}{ext}")
+
+
 async def append_raw_line(path: Path, line: str) -> None:
     """Append a single line to the raw output file."""
     async with aiofiles.open(path, "a") as fh:
         await fh.write(line + "\n")
 
 
-async def write_json_results(path: Path, findings: list[Finding]) -> None:
-    """Write findings as a JSON array."""
+class StreamingTextWriter:
+    """The raw text output, held open while a scan's output is read.
+
+    Used as an async context manager around the reading. Each line is
+    flushed as it is written, so a scan that is interrupted or crashes
+    still leaves every line read so far in the file.
+    """
+
+    def __init__(self, path: Path) -> None:
+        self.path = path
+        self._opener = None
+        self._fh = None
+
+    async def __aenter__(self) -> StreamingTextWriter:
+        self._opener = aiofiles.open(self.path, "a")
+        self._fh = await self._opener.__aenter__()
+        return self
+
+    async def __aexit__(self, *exc_info) -> None:
+        await self._opener.__aexit__(*exc_info)
+        self._opener = self._fh = None
+
+    async def write_line(self, line: str) -> None:
+        """Append line to the file and flush it."""
+        await self._fh.write(line + "\n")
+        await self._fh.flush()
+
+
+async def write_raw_header(path: Path, meta: ScanMeta) -> None:
+    """Start the raw output file with a commented metadata block."""
+    async with aiofiles.open(path, "a") as fh:
+        await fh.write("\n".join(meta.header_lines()) + "\n")
+
+
+def build_envelope(
+    findings: list[Finding],
+    meta: ScanMeta,
+    secrets: list[SecretMatch] | None = None,
+) -> dict[str, object]:
+    """Wrap findings in the versioned JSON envelope.
+
+    The envelope has "schema_version", "tool", "mode", "target", "meta",
+    "findings" and "secrets" keys. Bump SCHEMA_VERSION when its shape changes
+    incompatibly.
+    """
+    return {
+        "schema_version": SCHEMA_VERSION,
+        "tool": meta.tool,
+        "mode": meta.mode,
+        "target": meta.target,
+        "meta": asdict(meta),
+        "findings": [asdict(f) for f in findings],
+        "secrets": [asdict(s) for s in secrets or []],
+    }
+
+
+def dump_json(data: object, compact: bool = False) -> str:
+    """Serialise findings JSON, indented by two spaces or, if compact, on one line."""
+    if compact:
+        return json.dumps(data, separators=(",", ":"))
+    return json.dumps(data, indent=2)
+
+
+async def write_envelope(
+    path: Path,
+    findings: list[Finding],
+    meta: ScanMeta,
+    secrets: list[SecretMatch] | None = None,
+    compact: bool = False,
+) -> None:
+    """Write findings wrapped in the versioned JSON envelope (see build_envelope())."""
+    data = build_envelope(findings, meta, secrets)
+    async with aiofiles.open(path, "w") as fh:
+        await fh.write(dump_json(data, compact))
+
+
+def compress_file(path: Path) -> Path:
+    """Gzip path to path + ".gz", remove the original and return the new path.
+
+    The file is streamed through the compressor, so large raw logs are not
+    read into memory. Raises OSError if either file cannot be accessed.
+    """
+    target = path.with_name(path.name + ".gz")
+    with open(path, "rb") as src, gzip.open(target, "wb") as dst:
+        shutil.copyfileobj(src, dst)
+    path.unlink()
+    return target
+
+
+def finding_key(finding: Finding) -> str:
+    """Identify a finding across runs by its status, URL, inputs and vhost chain.
+
+    Sizes and timestamps are left out, so a page that merely changed length
+    is not reported as new, while one that changed status is.
+    """
+    inputs = ",".join(f"{k}={v}" for k, v in sorted(finding.inputs.items()))
+    return "|".join([str(finding.status_code), finding.url, inputs, ".".join(finding.vhost_chain)])
+
+
+def load_findings(path: str) -> list[Finding]:
+    """Read the findings of a findings JSON file.
+
+    Both the versioned envelope and the legacy bare array are accepted, as
+    is a .gz file written with --compress. Unknown keys are ignored. Raises
+    OSError if the file cannot be read and ValueError if it is not findings
+    JSON.
+    """
+    opener = gzip.open if path.endswith(".gz") else open
+    with opener(path, "rt") as fh:
+        data = json.load(fh)
+    return [_finding_from_dict(entry) for entry in _finding_entries(data) if isinstance(entry, dict)]
+
+
+def _finding_entries(data: object) -> list:
+    """Return the findings list of parsed findings JSON, envelope or bare array."""
+    entries = data.get("findings") if isinstance(data, dict) else data
+    if not isinstance(entries, list):
+        raise ValueError("no findings list")
+    return entries
+
+
+def _finding_from_dict(entry: dict) -> Finding:
+    """Build a Finding from one findings JSON entry, ignoring unknown keys.
+
+    A null value, as other tools writing this format may leave, takes the
+    field's default. Raises ValueError if inputs is not an object or
+    vhost_chain not a list.
+    """
+    names = {f.name for f in fields(Finding)}
+    finding = Finding(**{k: v for k, v in entry.items() if k in names and v is not None})
+    if not isinstance(finding.inputs, dict) or not isinstance(finding.vhost_chain, list):
+        raise ValueError("finding inputs must be an object and vhost_chain a list")
+    return finding
+
+
+def tag_key(finding: Finding) -> str:
+    """Identify a finding for tagging: its URL, or its inputs and vhost chain if it has none.
+
+    The status code is left out, so a tag follows a page whose status changed.
+    """
+    if finding.url:
+        return finding.url
+    inputs = ",".join(f"{k}={v}" for k, v in sorted(finding.inputs.items()))
+    return "|".join([inputs, ".".join(finding.vhost_chain)])
+
+
+def tags_path(json_path: Path) -> Path:
+    """Return the tags sidecar of a findings JSON file: x_tags.json beside x.json or x.json.gz."""
+    stem = json_path.name.removesuffix(".gz").removesuffix(".json")
+    return json_path.with_name(f"{stem}_tags.json")
+
+
+def load_tags(path: str) -> dict[str, str]:
+    """Read the tags of a findings JSON file, keyed by tag_key().
+
+    Tags recorded on the findings are read first, then any in the file's
+    tags sidecar, which take precedence. Raises OSError if a file cannot be
+    read and ValueError if either is not the expected JSON.
+    """
+    tags = {tag_key(f): f.tag for f in load_findings(path) if f.tag}
+    sidecar = tags_path(Path(path))
+    if sidecar.exists():
+        data = json.loads(sidecar.read_text())
+        if not isinstance(data, dict):
+            raise ValueError("tags file is not a JSON object")
+        tags.update({k: v for k, v in data.items() if isinstance(v, str) and v})
+    return tags
+
+
+def carry_tags(findings: list[Finding], tags: dict[str, str]) -> int:
+    """Give each untagged finding the tag recorded for its tag_key(), returning how many got one."""
+    carried = 0
+    for finding in findings:
+        if not finding.tag and tags.get(tag_key(finding)):
+            finding.tag = tags[tag_key(finding)]
+            carried += 1
+    return carried
+
+
+def write_tags(json_path: Path, findings: list[Finding]) -> Path:
+    """Record the findings' tags in their findings JSON file and its tags sidecar.
+
+    The JSON file (envelope or bare array, plain or gzipped) is rewritten
+    in place with each entry's tag, matched on tag_key(), keeping its other
+    content and its indented or compact layout, and the sidecar is
+    rewritten by write_tags_sidecar(). Returns the sidecar path. Raises
+    OSError if a file cannot be read or written and ValueError if the JSON
+    file holds no findings.
+    """
+    tags = {tag_key(f): f.tag for f in findings}
+    opener = gzip.open if json_path.name.endswith(".gz") else open
+    with opener(json_path, "rt") as fh:
+        text = fh.read()
+    data = json.loads(text)
+    for entry in _finding_entries(data):
+        if isinstance(entry, dict):
+            entry["tag"] = tags.get(tag_key(_finding_from_dict(entry)), entry.get("tag", ""))
+    with opener(json_path, "wt") as fh:
+        fh.write(dump_json(data, compact="\n" not in text.strip()))
+    return write_tags_sidecar(json_path, findings)
+
+
+def write_tags_sidecar(json_path: Path, findings: list[Finding]) -> Path:
+    """Write the tags sidecar of a findings JSON file, mapping tag_key() to tag.
+
+    Only tagged findings are listed. Entries already in the sidecar for
+    other findings, such as those dropped as unchanged by --baseline, are
+    kept. Returns the sidecar path. Raises OSError if it cannot be written.
+    """
+    sidecar = tags_path(json_path)
+    tags: dict[str, str] = {}
+    if sidecar.exists():
+        try:
+            data = json.loads(sidecar.read_text())
+        except ValueError:
+            data = {}
+        if isinstance(data, dict):
+            tags = {k: v for k, v in data.items() if isinstance(v, str) and v}
+    for finding in findings:
+        tags.pop(tag_key(finding), None)
+    tags.update({tag_key(f): f.tag for f in findings if f.tag})
+    sidecar.write_text(dump_json(tags))
+    return sidecar
+
+
+def load_finding_keys(path: str) -> set[str]:
+    """Read a findings JSON file and return the finding_key() of each finding.
+
+    Accepts the same files as load_findings().
+    """
+    return {finding_key(f) for f in load_findings(path)}
+
+
+def merge_finding_files(paths: list[str]) -> tuple[list[Finding], int]:
+    """Merge the findings of several findings JSON files.
+
+    Findings are deduplicated by finding_key(), so ffuf results without a
+    URL stay apart by their inputs, keeping the first seen in paths order,
+    and sorted by URL then status. Returns the merged findings and the
+    number of duplicates collapsed. Raises OSError or ValueError, naming
+    the file, as load_findings() does.
+    """
+    merged: dict[str, Finding] = {}
+    duplicates = 0
+    for path in paths:
+        try:
+            findings = load_findings(path)
+        except ValueError as exc:
+            # Subclasses such as JSONDecodeError take more than a message
+            raise ValueError(f"{path}: {exc}") from exc
+        except OSError as exc:
+            raise OSError(f"{path}: {exc}") from exc
+        for finding in findings:
+            key = finding_key(finding)
+            if key in merged:
+                duplicates += 1
+            else:
+                merged[key] = finding
+    return sorted(merged.values(), key=lambda f: (f.url, f.status_code, finding_key(f))), duplicates
+
+
+def write_merged(json_path: Path, findings: list[Finding], sources: list[str]) -> Path:
+    """Write merged findings as JSON, plus a text file of one finding per line.
+
+    The JSON has "schema_version", "merged_from" and "findings" keys, so it
+    can itself be read by load_findings(), e.g. as a --baseline. A finding
+    without a URL is listed in the text file by its inputs. Returns the
+    text file path. Raises OSError if either file cannot be written.
+    """
+    data = {
+        "schema_version": SCHEMA_VERSION,
+        "merged_from": sources,
+        "findings": [asdict(f) for f in findings],
+    }
+    json_path.write_text(json.dumps(data, indent=2))
+    text_path = json_path.with_suffix(".txt")
+    lines = []
+    for f in findings:
+        inputs = ", ".join(f"{k}={v}" for k, v in f.inputs.items())
+        lines.append(f"{f.status_code} {f.url or inputs or 'N/A'} [Size: {f.size}]\n")
+    text_path.write_text("".join(lines))
+    return text_path
+
+
+def mask_options(options: dict[str, str]) -> dict[str, str]:
+    """Return scan options with URL passwords and sensitive header values masked."""
+    masked = {key: mask_credentials(value) for key, value in options.items()}
+    if options.get("headers"):
+        masked["headers"] = "\n".join(mask_argv(options["headers"].splitlines()))
+    return masked
+
+
+def write_scan_config(
+    path: Path, meta: ScanMeta, options: dict[str, str], extra: dict[str, object] | None = None
+) -> None:
+    """Write the effective settings of a scan as JSON, so it can be audited or rerun.
+
+    Options are written after flags and config have been merged, with
+    secrets masked by mask_options(). extra holds further top-level keys,
+    such as the tool command line. Raises OSError if the file cannot be
+    written.
+    """
+    data = {
+        "krakenbuster_version": meta.krakenbuster_version,
+        "tool": meta.tool,
+        "tool_version": meta.tool_version,
+        "mode": meta.mode,
+        "target": meta.target,
+        "wordlist": meta.wordlist,
+        "start_time": meta.start_time,
+        "options": dict(sorted(mask_options(options).items())),
+        **(extra or {}),
+    }
+    path.write_text(json.dumps(data, indent=2) + "\n")
+
+
+async def write_json_results(path: Path, findings: list[Finding], compact: bool = False) -> None:
+    """Write findings as a bare JSON array (legacy format, see --legacy-json)."""
     data = [asdict(f) for f in findings]
     async with aiofiles.open(path, "w") as fh:
-        await fh.write(json.dumps(data, indent=2))
+        await fh.write(dump_json(data, compact))
+
+
+def _metric_labels(**labels: str) -> str:
+    """Format Prometheus labels, escaping backslashes, quotes and newlines."""
+    def escape(value: str) -> str:
+        return value.replace("\\", "\\\\").replace('"', '\\"').replace("\n", "\\n")
+
+    return "{" + ",".join(f'{key}="{escape(value)}"' for key, value in labels.items()) + "}"
+
+
+def write_metrics(path: Path, results: list[ScanResult]) -> None:
+    """Write scan outcomes in the Prometheus text exposition format.
+
+    One sample per scan is written for each metric, labelled with the tool,
+    mode and target. The output line rate counts every line the tool
+    printed; tools mostly print findings, so it is not a request rate. The
+    file is replaced atomically so a scraper never reads a partial write.
+    """
+    families: list[tuple[str, str, list[str]]] = [
+        ("krakenbuster_findings_total", "Findings reported by the scan.", []),
+        ("krakenbuster_findings_by_status", "Findings per HTTP status code.", []),
+        ("krakenbuster_scan_duration_seconds", "Wall-clock duration of the scan.", []),
+        ("krakenbuster_output_line_rate", "Tool output lines per second; not a request rate.", []),
+    ]
+    for result in results:
+        base = dict(tool=result.tool, mode=result.mode, target=result.target)
+        families[0][2].append(f"{_metric_labels(**base)} {len(result.findings)}")
+        for status, items in sorted(result.findings_by_status.items()):
+            labels = _metric_labels(**base, status=str(status))
+            families[1][2].append(f"{labels} {len(items)}")
+        families[2][2].append(f"{_metric_labels(**base)} {result.duration_seconds:.3f}")
+        rate = len(result.raw_lines) / result.duration_seconds if result.duration_seconds else 0.0
+        families[3][2].append(f"{_metric_labels(**base)} {rate:.3f}")
+
+    lines: list[str] = []
+    for name, help_text, samples in families:
+        lines.append(f"# HELP {name} {help_text}")
+        lines.append(f"# TYPE {name} gauge")
+        lines.extend(f"{name}{sample}" for sample in samples)
+
+    tmp = path.with_name(path.name + ".tmp")
+    tmp.write_text("\n".join(lines) + "\n")
+    tmp.replace(path)
+
+
+# Table --sqlite writes each scan mode's findings to
+SQLITE_FINDING_TABLES = {
+    "directory": "dir_findings",
+    "vhost": "vhost_findings",
+    "dns": "dns_findings",
+}
+
+_SQLITE_FINDING_COLUMNS = """
+    run_id INTEGER NOT NULL REFERENCES runs(id),
+    status_code INTEGER,
+    url TEXT,
+    size INTEGER,
+    words INTEGER,
+    lines INTEGER,
+    redirect TEXT,
+    found_at TEXT,
+    capture TEXT,
+    inputs TEXT,
+    vhost_chain TEXT,
+    body_hash TEXT,
+    encoding TEXT,
+    latency_ms INTEGER,
+    tag TEXT
+"""
+
+# Finding columns added since the first schema, with their types, which
+# _migrate_sqlite() adds to tables created by older versions
+SQLITE_ADDED_COLUMNS = [("encoding", "TEXT"), ("latency_ms", "INTEGER"), ("tag", "TEXT")]
+
+SQLITE_SCHEMA = """
+CREATE TABLE IF NOT EXISTS runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    krakenbuster_version TEXT,
+    tool TEXT,
+    tool_version TEXT,
+    mode TEXT,
+    target TEXT,
+    wordlist TEXT,
+    start_time TEXT,
+    end_time TEXT,
+    duration_seconds REAL,
+    failed INTEGER
+);
+""" + "".join(
+    f"CREATE TABLE IF NOT EXISTS {table} ({_SQLITE_FINDING_COLUMNS});\n"
+    f"CREATE INDEX IF NOT EXISTS {table}_run ON {table} (run_id);\n"
+    for table in SQLITE_FINDING_TABLES.values()
+)
+
+
+def _migrate_sqlite(db: sqlite3.Connection) -> None:
+    """Add any SQLITE_ADDED_COLUMNS missing from the finding tables."""
+    for table in SQLITE_FINDING_TABLES.values():
+        existing = {row[1] for row in db.execute(f"PRAGMA table_info({table})")}
+        for column, kind in SQLITE_ADDED_COLUMNS:
+            if column not in existing:
+                db.execute(f"ALTER TABLE {table} ADD COLUMN {column} {kind}")
+
+
+def write_sqlite(path: Path, results: list[ScanResult]) -> None:
+    """Add each scan and its findings to a SQLite database for cross-run queries.
+
+    The schema (SQLITE_SCHEMA) is created on first use, and a database
+    from an older version gains the newer finding columns. Every scan becomes
+    a row in runs, and its findings go to the mode's table in
+    SQLITE_FINDING_TABLES keyed by that row's id, with inputs and
+    vhost_chain stored as JSON. Everything is inserted in one transaction,
+    so an interrupted write adds nothing. Raises sqlite3.Error if the
+    database cannot be opened or written.
+    """
+    with closing(sqlite3.connect(path)) as db:
+        db.executescript(SQLITE_SCHEMA)
+        with db:
+            _migrate_sqlite(db)
+            for result in results:
+                meta = getattr(result, "_meta", None) or ScanMeta()
+                cursor = db.execute(
+                    "INSERT INTO runs (krakenbuster_version, tool, tool_version, mode, target,"
+                    " wordlist, start_time, end_time, duration_seconds, failed)"
+                    " VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+                    (
+                        meta.krakenbuster_version, result.tool, meta.tool_version, result.mode,
+                        result.target, result.wordlist, meta.start_time, meta.end_time,
+                        result.duration_seconds, int(result.failed),
+                    ),
+                )
+                table = SQLITE_FINDING_TABLES.get(result.mode)
+                if not table:
+                    continue
+                db.executemany(
+                    f"INSERT INTO {table} (run_id, status_code, url, size, words, lines, redirect,"
+                    " found_at, capture, inputs, vhost_chain, body_hash, encoding, latency_ms, tag)"
+                    " VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+                    [
+                        (
+                            cursor.lastrowid, f.status_code, f.url, f.size, f.words, f.lines,
+                            f.redirect, f.found_at, f.capture, json.dumps(f.inputs),
+                            json.dumps(f.vhost_chain), f.body_hash, f.encoding, f.latency_ms, f.tag,
+                        )
+                        for f in result.findings
+                    ],
+                )
+
+
+def should_capture(finding: Finding) -> bool:
+    """Return True for findings worth capturing: 200 OK and server errors."""
+    return bool(finding.url) and (finding.status_code == 200 or finding.status_code >= 500)
+
+
+# Sent with capture requests, as browsers and the scan tools' HTTP clients
+# do, so the capture shows whether the server compresses its responses
+CAPTURE_ACCEPT_ENCODING = "gzip, deflate, br"
+
+
+def _decode_body(body: bytes, encoding: str, max_bytes: int) -> bytes:
+    """Decompress a gzip or deflate body, truncated or not, to at most max_bytes.
+
+    Other encodings (such as br) and bodies that fail to decompress are
+    returned as they are.
+    """
+    if encoding == "gzip":
+        wbits = [16 + zlib.MAX_WBITS]
+    elif encoding == "deflate":
+        # Servers send deflate both zlib-wrapped and raw
+        wbits = [zlib.MAX_WBITS, -zlib.MAX_WBITS]
+    else:
+        return body
+    for bits in wbits:
+        try:
+            return zlib.decompressobj(bits).decompress(body, max_bytes)
+        except zlib.error:
+            continue
+    return body
+
+
+def capture_response(
+    url: str, client: HttpClient, max_bytes: int, capture_dir: Path
+) -> tuple[Path, str, int]:
+    """Fetch a URL and store its status, headers, and first max_bytes of body.
+
+    The capture is written to capture_dir under a name derived from a hash
+    of the URL. A gzip or deflate body is stored decompressed. Returns the
+    capture path, the response's Content-Encoding ("" if none) and the
+    milliseconds taken to fetch it. Raises OSError on request failure.
+    """
+    start = time.monotonic()
+    resp = client.get(url, {"Accept-Encoding": CAPTURE_ACCEPT_ENCODING}, max_bytes=max_bytes)
+    latency_ms = round((time.monotonic() - start) * 1000)
+    capture_dir.mkdir(parents=True, exist_ok=True)
+    path = capture_dir / f"{hashlib.sha256(url.encode()).hexdigest()[:16]}.txt"
+    encoding = next(
+        (value.strip().lower() for name, value in resp.headers.items()
+         if name.lower() == "content-encoding"),
+        "",
+    )
+
+    head = [f"GET {url}", f"HTTP {resp.status_code}"]
+    head.extend(f"{name}: {value}" for name, value in resp.headers.items())
+    with open(path, "wb") as fh:
+        fh.write(("\n".join(head) + "\n\n").encode("utf-8", errors="replace"))
+        fh.write(_decode_body(resp.body, encoding, max_bytes))
+    return path, encoding, latency_ms
+
+
+def hash_and_group(
+    findings: list[Finding], client: HttpClient, workers: int = 4
+) -> dict[str, list[str]]:
+    """Hash the body of each 200 finding and group URLs serving identical bodies.
+
+    Each finding's body_hash is set. Bodies are fetched on up to `workers`
+    threads, reading at most HASH_MAX_BYTES each; URLs that cannot be
+    fetched are skipped. Returns {sha256: [url, ...]} for hashes shared by
+    two or more URLs.
+    """
+    urls = list(dict.fromkeys(f.url for f in findings if f.url and f.status_code == 200))
+
+    def fetch(url: str) -> str:
+        try:
+            return hashlib.sha256(client.get(url, max_bytes=HASH_MAX_BYTES).body).hexdigest()
+        except OSError:
+            return ""
+
+    with ThreadPoolExecutor(max_workers=max(workers, 1)) as pool:
+        digests = dict(zip(urls, pool.map(fetch, urls)))
+
+    groups: dict[str, list[str]] = {}
+    for url, digest in digests.items():
+        if digest:
+            groups.setdefault(digest, []).append(url)
+    for finding in findings:
+        finding.body_hash = digests.get(finding.url, "") if finding.status_code == 200 else ""
+    return {digest: group for digest, group in groups.items() if len(group) > 1}
+
+
+def scan_for_secrets(body: bytes) -> list[SecretMatch]:
+    """Return likely secrets in a response body, each distinct value once."""
+    matches: list[SecretMatch] = []
+    seen: set[bytes] = set()
+    for kind, pattern in SECRET_PATTERNS:
+        for match in pattern.finditer(body):
+            if match.group(0) not in seen:
+                seen.add(match.group(0))
+                matches.append(SecretMatch(kind, match.group(0).decode("ascii", errors="replace")))
+    return matches
+
+
+def mark_size_anomalies(findings: list[Finding]) -> set[int]:
+    """Return indexes of findings whose size stands out within their status class.
+
+    Findings are grouped by status class (2xx, 3xx, ...). A size is anomalous
+    when it lies more than ANOMALY_SIGMA population standard deviations from
+    the group median. Groups smaller than ANOMALY_MIN_GROUP are skipped, as
+    are groups where every size is the same.
+    """
+    groups: dict[int, list[int]] = {}
+    for index, finding in enumerate(findings):
+        groups.setdefault(finding.status_code // 100, []).append(index)
+
+    anomalies: set[int] = set()
+    for indexes in groups.values():
+        if len(indexes) < ANOMALY_MIN_GROUP:
+            continue
+        sizes = [findings[i].size for i in indexes]
+        sigma = statistics.pstdev(sizes)
+        if sigma == 0:
+            continue
+        median = statistics.median(sizes)
+        anomalies.update(
+            i for i in indexes if abs(findings[i].size - median) > ANOMALY_SIGMA * sigma
+        )
+    return anomalies
+
+
+def slowest_findings(findings: list[Finding], count: int = SLOWEST_SHOWN) -> list[Finding]:
+    """Return up to count captured findings, slowest response first.
+
+    Slow responses often come from dynamic pages doing real work, so they
+    are worth a look. Findings without a recorded latency are left out.
+    """
+    timed = [f for f in findings if f.latency_ms > 0]
+    return sorted(timed, key=lambda f: -f.latency_ms)[:count]
+
+
+def detect_redirect_loops(findings: list[Finding]) -> set[int]:
+    """Return indexes of findings whose redirect leads back to themselves.
+
+    A finding loops when its redirect (resolved against its URL, ignoring
+    any fragment) is its own URL, or when following the redirects of other
+    findings returns to it. A redirect to a different URL that is never
+    found, such as /images to /images/, is not a loop.
+    """
+    redirects: dict[str, str] = {}
+    for finding in findings:
+        if finding.url and finding.redirect:
+            redirects[urldefrag(finding.url)[0]] = urldefrag(urljoin(finding.url, finding.redirect))[0]
+
+    loops: set[int] = set()
+    for index, finding in enumerate(findings):
+        start = urldefrag(finding.url)[0]
+        current = redirects.get(start)
+        seen: set[str] = set()
+        while current is not None and current != start and current not in seen:
+            seen.add(current)
+            current = redirects.get(current)
+        if current == start:
+            loops.add(index)
+    return loops
+
+
+def highlight_interesting(
+    findings: list[Finding], keywords: tuple[str, ...] | list[str] = DEFAULT_INTERESTING_KEYWORDS
+) -> list[Finding]:
+    """Return findings whose path contains an interesting keyword.
+
+    Matching ignores case, and a keyword must start a path word (after "/",
+    ".", "-" or "_"), so "api" matches /api and /v1/api-docs but not
+    /capital. Findings without a URL are matched on their ffuf inputs.
+    """
+    if not keywords:
+        return []
+    pattern = re.compile(
+        r"(?:^|[/._-])(?:" + "|".join(re.escape(k.lower().lstrip("/")) for k in keywords) + ")",
+        re.IGNORECASE,
+    )
+    matched = []
+    for finding in findings:
+        text = urlparse(finding.url).path if finding.url else " ".join(finding.inputs.values())
+        if pattern.search(text):
+            matched.append(finding)
+    return matched
+
+
+def cluster_vhosts(findings: list[Finding]) -> list[VhostCluster]:
+    """Group vhost findings by (status, size, words), largest cluster first.
+
+    Only groups of at least VHOST_CLUSTER_MIN findings are returned.
+    """
+    groups: dict[tuple[int, int, int], list[Finding]] = {}
+    for finding in findings:
+        groups.setdefault((finding.status_code, finding.size, finding.words), []).append(finding)
+    clusters = [
+        VhostCluster(status, size, words, members)
+        for (status, size, words), members in groups.items()
+        if len(members) >= VHOST_CLUSTER_MIN
+    ]
+    clusters.sort(key=lambda c: (-len(c.findings), c.status_code, c.size))
+    return clusters
+
+
+def collapse_clusters(findings: list[Finding], clusters: list[VhostCluster]) -> list[Finding]:
+    """Keep only the first finding of each cluster, preserving order."""
+    dropped = {id(f) for cluster in clusters for f in cluster.findings[1:]}
+    return [f for f in findings if id(f) not in dropped]
+
+
+def filter_by_status(findings: list[Finding], codes: list[int]) -> list[Finding]:
+    """Keep only findings whose status code is in codes (all if codes is empty)."""
+    if not codes:
+        return list(findings)
+    wanted = set(codes)
+    return [f for f in findings if f.status_code in wanted]
+
+
+def filter_by_status_range(findings: list[Finding], low: int = 0, high: int = 0) -> list[Finding]:
+    """Keep only findings with low <= status code <= high; a bound of 0 is open."""
+    return [
+        f for f in findings
+        if (not low or f.status_code >= low) and (not high or f.status_code <= high)
+    ]
+
+
+def filter_findings(findings: list[Finding], query: str) -> list[Finding]:
+    """Keep the findings matching every term of a filter query.
+
+    Terms are separated by spaces: a status code ("200") or class ("3xx"),
+    a size bound (">1000", "<=512"), a tag ("tag:interesting", or "tag:"
+    for untagged findings), or any other text, matched case-insensitively
+    against the URL and inputs. An empty query keeps everything.
+    """
+    result = list(findings)
+    for term in query.lower().split():
+        if term.startswith("tag:"):
+            result = [f for f in result if f.tag == term[4:]]
+        elif re.fullmatch(r"\d{3}", term):
+            result = [f for f in result if f.status_code == int(term)]
+        elif re.fullmatch(r"[1-5]xx", term):
+            result = [f for f in result if f.status_code // 100 == int(term[0])]
+        elif match := re.fullmatch(r"(<=|>=|<|>)(\d+)", term):
+            op, bound = match.group(1), int(match.group(2))
+            compare = {
+                "<": lambda size: size < bound,
+                "<=": lambda size: size <= bound,
+                ">": lambda size: size > bound,
+                ">=": lambda size: size >= bound,
+            }[op]
+            result = [f for f in result if compare(f.size)]
+        else:
+            result = [
+                f for f in result
+                if term in f.url.lower() or any(term in v.lower() for v in f.inputs.values())
+            ]
+    return result
 
 
 def parse_status_code(line: str) -> int | None:
@@ -126,6 +1205,24 @@ def parse_url(line: str) -> str:
     return ""
 
 
+def parse_redirect(line: str) -> str:
+    """Extract the redirect target from a tool output line, or "".
+
+    Handles feroxbuster's "url => target", gobuster's "[--> target]" and
+    dirsearch's "-> REDIRECTS TO: target".
+    """
+    patterns = [
+        r"\[-->\s*([^\]\s]+)\]",           # gobuster: [--> http://t/images/]
+        r"REDIRECTS TO:\s*(\S+)",          # dirsearch
+        r"\s=>\s*(\S+)",                    # feroxbuster: http://t/images => http://t/images/
+    ]
+    for pattern in patterns:
+        match = re.search(pattern, line)
+        if match:
+            return match.group(1)
+    return ""
+
+
 def parse_size(line: str) -> int:
     """Extract response size from a tool output line."""
     patterns = [
@@ -141,6 +1238,22 @@ def parse_size(line: str) -> int:
     return 0
 
 
+def parse_words(line: str) -> int:
+    """Extract the response word count from a tool output line, or 0."""
+    # Only the tools' own count columns are matched: a bare "3w" could be
+    # part of a host or path, such as 3w.example.com
+    patterns = [
+        r"Words:\s*(\d+)",                      # ffuf: [Status: 200, Size: 4521, Words: 10, ...]
+        r"\b\d+l\s+(\d+)w\s+\d+c\b",            # feroxbuster: 200 GET 10l 28w 300c
+        r"\b\d+\s+L\s+(\d+)\s+W\s+\d+\s+Ch\b",  # wfuzz: 200 7 L 12 W 178 Ch
+    ]
+    for pattern in patterns:
+        match = re.search(pattern, line)
+        if match:
+            return int(match.group(1))
+    return 0
+
+
 def parse_finding(line: str) -> Finding | None:
     """Parse a single output line into a Finding, if it contains one."""
     status = parse_status_code(line)
@@ -149,11 +1262,19 @@ def parse_finding(line: str) -> Finding | None:
 
     url = parse_url(line)
     size = parse_size(line)
+    # gobuster and dirsearch print only the path, so their one URL is the
+    # redirect target; a redirect is only recorded alongside a separate source
+    redirect = parse_redirect(line) if 300 <= status < 400 else ""
+    if redirect and not parse_url(line[:line.rfind(redirect)]):
+        redirect = ""
 
     return Finding(
         status_code=status,
         url=url,
         size=size,
+        words=parse_words(line),
+        redirect=redirect,
+        found_at=utc_timestamp(),
     )
 
 
@@ -191,6 +1312,107 @@ def parse_progress(line: str) -> tuple[int, int] | None:
     return None
 
 
+_ANSI_ESCAPE = re.compile(r"\x1b\[[0-9;?]*[A-Za-z]")
+
+
+def strip_ansi(line: str) -> str:
+    """Remove ANSI escape sequences such as colours and line clears."""
+    return _ANSI_ESCAPE.sub("", line)
+
+
+def parse_ffuf_progress(line: str) -> tuple[int, int] | None:
+    """Parse ffuf's ":: Progress: [done/total] :: ..." status line.
+
+    Returns (done, total), or None if the line is not a usable progress line.
+    """
+    match = re.search(r":: Progress: \[(\d+)/(\d+)\]", strip_ansi(line))
+    if not match:
+        return None
+    done, total = int(match.group(1)), int(match.group(2))
+    if total <= 0 or done > total:
+        return None
+    return done, total
+
+
+def parse_ffuf_input(line: str) -> tuple[str, str] | None:
+    """Parse one "* KEYWORD: value" line that ffuf prints under a result.
+
+    ffuf switches to this multi-line layout when more than one wordlist
+    keyword is in use. Returns (keyword, value), or None for other lines.
+    """
+    match = re.match(r"^\s*\*\s+([A-Za-z0-9_]+):\s?(.*)$", strip_ansi(line))
+    if not match:
+        return None
+    return match.group(1), match.group(2)
+
+
+def _ffuf_int(value: object) -> int:
+    """Read a count from ffuf JSON, which some builds write as a string."""
+    try:
+        return int(value)  # type: ignore[arg-type]
+    except (TypeError, ValueError):
+        return 0
+
+
+def _ffuf_inputs(value: object) -> dict[str, str]:
+    """Normalise a result's "input" field to {keyword: value}.
+
+    Builds differ: current ffuf writes a {"FUZZ": "admin"} object, older
+    ones a bare string for the single FUZZ keyword, and some nest each value
+    as {"value": ...}. ffuf's internal FFUFHASH entry is dropped.
+    """
+    if isinstance(value, str):
+        return {"FUZZ": value}
+    if not isinstance(value, dict):
+        return {}
+    inputs: dict[str, str] = {}
+    for keyword, item in value.items():
+        if keyword == "FFUFHASH":
+            continue
+        if isinstance(item, dict):
+            item = item.get("value", "")
+        inputs[str(keyword)] = str(item)
+    return inputs
+
+
+def parse_ffuf_json(text: str) -> list[Finding]:
+    """Parse the results of ffuf's -of json output into findings.
+
+    Differences between ffuf builds are tolerated: key casing (length vs
+    Length), numbers written as strings, and the shapes handled by
+    _ffuf_inputs(). Entries that are not objects are skipped. Raises
+    ValueError if text is not JSON.
+    """
+    data = json.loads(text)
+    results = data.get("results") if isinstance(data, dict) else None
+    findings: list[Finding] = []
+    for entry in results if isinstance(results, list) else []:
+        if not isinstance(entry, dict):
+            continue
+        fields = {str(key).lower(): value for key, value in entry.items()}
+        findings.append(Finding(
+            status_code=_ffuf_int(fields.get("status")),
+            url=str(fields.get("url") or ""),
+            size=_ffuf_int(fields.get("length")),
+            words=_ffuf_int(fields.get("words")),
+            lines=_ffuf_int(fields.get("lines")),
+            redirect=str(fields.get("redirectlocation") or ""),
+            found_at=utc_timestamp(),
+            inputs=_ffuf_inputs(fields.get("input")),
+        ))
+    return findings
+
+
+def parse_ffuf_word(line: str) -> str | None:
+    """Return the input word at the start of an ffuf result line.
+
+    ffuf's default layout is "word   [Status: 200, Size: ...]"; the word is
+    the only place a vhost result names the host it found.
+    """
+    match = re.match(r"^\s*(\S+)\s+\[Status:", strip_ansi(line))
+    return match.group(1) if match else None
+
+
 def parse_dirb_downloaded(line: str) -> int | None:
     """Parse dirb's final DOWNLOADED count from output."""
     match = re.search(r"DOWNLOADED:\s*(\d+)", line)
@@ -0,0 +1,305 @@
+"""Direct HTTP probes made by KrakenBuster itself, outside the wrapped tools."""
+
+from __future__ import annotations
+
+import random
+import re
+import ssl
+import urllib.error
+import urllib.request
+import xml.etree.ElementTree as ET
+from dataclasses import dataclass, field
+from urllib.parse import urljoin, urlparse
+
+from krakenbuster.output import Finding
+
+
+# Source for random probe paths, Host names, --random-agent choices and the
+# --shuffle-wordlist order.
+# Reseeded by --seed so runs can be reproduced; pass an explicit rng to the
+# probes to override it.
+rng = random.Random()
+
+
+def seed_random(seed: int) -> None:
+    """Reseed the package random source so random probes repeat across runs."""
+    rng.seed(seed)
+
+
+def random_token(source: random.Random | None = None, length: int = 32) -> str:
+    """Return length random lowercase hex characters from source (default rng)."""
+    return f"{(source or rng).getrandbits(length * 4):0{length}x}"
+
+
+@dataclass
+class Baseline:
+    """Response recorded for a path that should not exist on the target.
+
+    This is the soft-404 probe of --auto-filter. The --baseline flag is
+    unrelated: it names an earlier findings file, read by
+    output.load_finding_keys().
+    """
+
+    url: str = ""
+    status_code: int = 0
+    size: int = 0
+    words: int = 0
+
+    def matches(self, finding: Finding) -> bool:
+        """Return True if a finding looks like the soft-404 baseline.
+
+        It must have the baseline's status and either its size or, when the
+        tool reports one, its word count: a soft-404 page that echoes the
+        requested path changes size from path to path but not word count.
+        """
+        if finding.status_code != self.status_code:
+            return False
+        return finding.size == self.size or bool(finding.words and finding.words == self.words)
+
+
+@dataclass
+class HttpResponse:
+    """A response fetched by HttpClient."""
+
+    status_code: int = 0
+    headers: dict[str, str] = field(default_factory=dict)
+    body: bytes = b""
+
+
+class HttpClient:
+    """HTTP client for KrakenBuster's own probes.
+
+    Wraps a urllib opener so every probe honours the same proxy, TLS
+    verification, timeout and extra header settings. Create one with
+    new_http_client().
+    """
+
+    def __init__(
+        self,
+        opener: urllib.request.OpenerDirector,
+        timeout: float,
+        headers: dict[str, str] | None = None,
+    ) -> None:
+        self.opener = opener
+        self.timeout = timeout
+        self.headers = headers or {}
+
+    def get(
+        self,
+        url: str,
+        headers: dict[str, str] | None = None,
+        max_bytes: int | None = None,
+    ) -> HttpResponse:
+        """Fetch a URL, treating HTTP error statuses as normal responses.
+
+        headers are sent on top of the client's own. Reads at most max_bytes
+        of the body when given. Raises OSError (including
+        urllib.error.URLError) if the request cannot be made.
+        """
+        request = urllib.request.Request(url, headers={**self.headers, **(headers or {})})
+        try:
+            with self.opener.open(request, timeout=self.timeout) as resp:
+                body = resp.read(max_bytes) if max_bytes is not None else resp.read()
+                return HttpResponse(resp.status, dict(resp.headers.items()), body)
+        except urllib.error.HTTPError as exc:
+            body = exc.read(max_bytes) if max_bytes is not None else exc.read()
+            return HttpResponse(exc.code, dict(exc.headers.items()), body)
+
+
+def new_http_client(options: dict[str, str]) -> HttpClient:
+    """Build an HttpClient from scan options (proxy, insecure, timeout, headers)."""
+    handlers: list[urllib.request.BaseHandler] = []
+
+    proxy = options.get("proxy", "")
+    if proxy:
+        handlers.append(urllib.request.ProxyHandler({"http": proxy, "https": proxy}))
+
+    if options.get("insecure", "false").lower() in ("true", "1", "yes", "on"):
+        context = ssl.create_default_context()
+        context.check_hostname = False
+        context.verify_mode = ssl.CERT_NONE
+        handlers.append(urllib.request.HTTPSHandler(context=context))
+
+    try:
+        timeout = float(options.get("timeout", "10"))
+    except ValueError:
+        timeout = 10.0
+
+    headers = {}
+    for header in options.get("headers", "").splitlines():
+        name, _, value = header.partition(":")
+        if name.strip():
+            headers[name.strip()] = value.strip()
+
+    return HttpClient(urllib.request.build_opener(*handlers), timeout, headers)
+
+
+def probe_baseline(
+    target: str, client: HttpClient, source: random.Random | None = None
+) -> Baseline:
+    """Request a random nonexistent path and record the response as a baseline.
+
+    The path is drawn from source, or the package rng when None. Raises
+    OSError if the target cannot be reached, or ValueError if it is not an
+    http(s) URL.
+    """
+    url = f"{target.rstrip('/')}/{random_token(source)}"
+    resp = client.get(url)
+    return Baseline(
+        url=url,
+        status_code=resp.status_code,
+        size=len(resp.body),
+        words=len(resp.body.split()),
+    )
+
+
+def probe_vhost_baseline(
+    target: str, domain: str, client: HttpClient, source: random.Random | None = None
+) -> Baseline:
+    """Request the target with a random subdomain of domain as the Host header.
+
+    A server that answers unknown vhosts with a catch-all page returns that
+    page here, giving a baseline to filter from the vhost scan. The
+    subdomain is drawn from source, or the package rng when None. Raises
+    OSError if the target cannot be reached, or ValueError if it is not an
+    http(s) URL.
+    """
+    host = f"{random_token(source, 16)}.{domain}"
+    resp = client.get(target, headers={"Host": host})
+    return Baseline(
+        url=host,
+        status_code=resp.status_code,
+        size=len(resp.body),
+        words=len(resp.body.split()),
+    )
+
+
+# Technology fingerprints checked by detect_extensions(), each with the file
+# extensions worth adding when it matches the root page's headers or body.
+TECH_EXTENSIONS: list[tuple[str, re.Pattern[str], list[str]]] = [
+    ("PHP", re.compile(r"X-Powered-By:[^\n]*PHP|PHPSESSID|\.php\b", re.I), ["php"]),
+    ("ASP.NET", re.compile(
+        r"X-Powered-By:[^\n]*ASP\.NET|X-AspNet(?:Mvc)?-Version:|Server:[^\n]*Microsoft-IIS"
+        r"|ASP\.NET_SessionId|__VIEWSTATE", re.I,
+    ), ["aspx", "asp", "ashx", "asmx"]),
+    ("Java", re.compile(
+        r"Server:[^\n]*(?:Apache-Coyote|Tomcat|Jetty)|X-Powered-By:[^\n]*(?:Servlet|JSP)"
+        r"|JSESSIONID|\.jsp\b", re.I,
+    ), ["jsp", "do", "action"]),
+    ("ColdFusion", re.compile(r"CFID|CFTOKEN|\.cfm\b", re.I), ["cfm"]),
+]
+
+# Body bytes inspected for technology hints
+DETECT_BODY_BYTES = 65536
+
+
+def detect_extensions(target: str, client: HttpClient) -> tuple[list[str], list[str]]:
+    """Guess useful file extensions from the target's root page.
+
+    The Server and X-Powered-By headers, session cookie names and the start
+    of the body are matched against TECH_EXTENSIONS. Returns the matched
+    technology names and their extensions, either of which may be empty.
+    Raises OSError if the target cannot be reached.
+    """
+    resp = client.get(target, max_bytes=DETECT_BODY_BYTES)
+    text = "\n".join(f"{name}: {value}" for name, value in resp.headers.items())
+    text += "\n" + resp.body.decode("utf-8", errors="replace")
+
+    technologies: list[str] = []
+    extensions: list[str] = []
+    for name, pattern, exts in TECH_EXTENSIONS:
+        if pattern.search(text):
+            technologies.append(name)
+            extensions.extend(e for e in exts if e not in extensions)
+    return technologies, extensions
+
+
+# Body bytes read from robots.txt and sitemap.xml by --use-robots
+SEED_BODY_BYTES = 1 << 20
+
+_ROBOTS_RULE = re.compile(r"^\s*(?:allow|disallow)\s*:\s*(\S+)", re.I | re.M)
+
+
+def _seed_path(path: str, base: str) -> str:
+    """Return path relative to the scan's base path, or "" if it lies outside it.
+
+    Anything from the first robots.txt wildcard ("*" or "$") on is dropped.
+    """
+    path = re.split(r"[*$]", path, maxsplit=1)[0]
+    if not path.startswith(base):
+        return ""
+    return path[len(base):].strip("/")
+
+
+def _seed_base(target: str) -> str:
+    """Return the target's path as a prefix ending in "/"."""
+    return urlparse(target).path.rstrip("/") + "/"
+
+
+def seed_from_robots(target: str, client: HttpClient) -> list[str]:
+    """Return the paths named by Allow and Disallow rules in the target's /robots.txt.
+
+    Paths are made relative to the target's own path, so they can be used
+    as wordlist entries; rules outside it, and "/" itself, are skipped.
+    Duplicates are dropped, keeping file order. A robots.txt that does not
+    answer 200 gives no paths. Raises OSError if the target cannot be
+    reached.
+    """
+    resp = client.get(urljoin(target, "/robots.txt"), max_bytes=SEED_BODY_BYTES)
+    if resp.status_code != 200:
+        return []
+    base = _seed_base(target)
+    text = resp.body.decode("utf-8", errors="replace")
+    paths = (_seed_path(match, base) for match in _ROBOTS_RULE.findall(text))
+    return list(dict.fromkeys(p for p in paths if p))
+
+
+def seed_from_sitemap(target: str, client: HttpClient) -> list[str]:
+    """Return the paths of the same-host <loc> URLs in the target's /sitemap.xml.
+
+    Paths are made relative to the target's path as in seed_from_robots().
+    A sitemap index is not followed into its child sitemaps. A sitemap that
+    does not answer 200 or is not XML gives no paths. Raises OSError if the
+    target cannot be reached.
+    """
+    resp = client.get(urljoin(target, "/sitemap.xml"), max_bytes=SEED_BODY_BYTES)
+    if resp.status_code != 200:
+        return []
+    try:
+        root = ET.fromstring(resp.body)
+    except ET.ParseError:
+        return []
+    host = urlparse(target).netloc.lower()
+    base = _seed_base(target)
+    paths = []
+    for element in root.iter():
+        # Tags carry the sitemap namespace, e.g. {http://...}loc
+        if element.tag.rpartition("}")[2] != "loc" or not element.text:
+            continue
+        url = urlparse(element.text.strip())
+        if url.netloc.lower() == host:
+            paths.append(_seed_path(url.path, base))
+    return list(dict.fromkeys(p for p in paths if p))
+
+
+def resolve_scheme(target: str, client: HttpClient) -> str:
+    """Prefix a scheme-less target with https:// or http://, whichever responds.
+
+    https is tried first. Any HTTP response counts, as does a TLS certificate
+    error, since that still shows an HTTPS server is listening. Raises
+    OSError if neither scheme gets an answer.
+    """
+    errors = []
+    for scheme in ("https", "http"):
+        url = f"{scheme}://{target}"
+        try:
+            client.get(url, max_bytes=0)
+        except urllib.error.URLError as exc:
+            if isinstance(exc.reason, ssl.SSLCertVerificationError):
+                return url
+            errors.append(f"{scheme}: {exc.reason}")
+        except OSError as exc:
+            errors.append(f"{scheme}: {exc}")
+        else:
+            return url
+    raise OSError(f"no response from {target} over https or http ({'; '.join(errors)})")
@@ -0,0 +1,1042 @@
+"""Scan orchestration shared by the CLI and the library API.
+
+run_cli_scan() runs one tool against one target, filtering, writing and
+reporting its findings; run_combined() runs directory and vhost scans
+across many hosts. Everything they print goes to the console in their
+ScanSettings, and every tool is started through its executor, so callers
+choose both per run.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import codecs
+import os
+import re
+import shlex
+import sys
+import time
+from dataclasses import asdict, dataclass, field, replace
+from datetime import datetime
+from pathlib import Path
+from typing import Awaitable
+from urllib.parse import urlparse
+
+from rich.console import Console
+from rich.markup import escape
+from rich.panel import Panel
+from rich.table import Table
+
+from krakenbuster.config import load_config, load_interesting_keywords, load_status_colours
+from krakenbuster.log import emit_event, logger
+from krakenbuster.output import (
+    RUN_TIMESTAMP_FORMAT,
+    Finding,
+    FindingStore,
+    ScanMeta,
+    ScanResult,
+    SecretMatch,
+    StreamingTextWriter,
+    append_raw_line,
+    capture_response,
+    carry_tags,
+    cluster_vhosts,
+    collapse_clusters,
+    compress_file,
+    detect_redirect_loops,
+    expand_output_dir,
+    filter_by_status,
+    filter_by_status_range,
+    finding_key,
+    generate_output_paths,
+    hash_and_group,
+    highlight_interesting,
+    mark_size_anomalies,
+    mask_argv,
+    mask_credentials,
+    parse_ffuf_input,
+    parse_ffuf_json,
+    parse_ffuf_progress,
+    parse_ffuf_word,
+    parse_finding,
+    sanitise_hostname,
+    scan_for_secrets,
+    should_capture,
+    slowest_findings,
+    status_colour,
+    strip_ansi,
+    utc_timestamp,
+    write_envelope,
+    write_json_results,
+    write_raw_header,
+    write_scan_config,
+    write_tags_sidecar,
+)
+from krakenbuster.probe import Baseline, new_http_client
+from krakenbuster.scanners.base import Executor, create_scanner, exec_executor
+from krakenbuster.scanners.feroxbuster import latest_state_file
+from krakenbuster.scanners.helpers import classify_stderr, tool_version
+
+# Most response bodies fetched at once by --hash-bodies, whatever --threads is
+HASH_WORKERS = 10
+
+# Output lines without a finding that, in a scan with no findings at all,
+# suggest the tool's output format has changed; banners stay well below this
+UNPARSED_WARN_LINES = 50
+
+# Tools that can write their own JSON output for --keep-raw
+RAW_OUTPUT_TOOLS = ["feroxbuster", "ffuf"]
+
+# Environment variables shown by --verbose, as they change how tools run
+VERBOSE_ENV_VARS = [
+    "HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "NO_PROXY",
+    "http_proxy", "https_proxy", "all_proxy", "no_proxy",
+    "NO_COLOR", "FORCE_COLOR",
+]
+
+
+@dataclass
+class TargetSpec:
+    """One combined-mode target, with optional per-target overrides."""
+
+    url: str
+    domain: str = ""  # overrides --domain
+    wordlist: str = ""  # overrides --wordlist
+
+
+@dataclass
+class HostResult:
+    """Outcome of the directory and vhost scans for one combined-mode host."""
+
+    host: str
+    dir_result: ScanResult | None = None
+    vhost_result: ScanResult | None = None
+    dir_error: str = ""
+    vhost_error: str = ""
+    duration_seconds: float = 0.0
+
+    @property
+    def total_findings(self) -> int:
+        return sum(len(scan.findings) for scan in (self.dir_result, self.vhost_result) if scan)
+
+
+@dataclass
+class ScanSettings:
+    """How run_cli_scan() filters, writes and reports one scan, beyond the tool's options.
+
+    baseline is the soft-404 probe from --auto-filter, while known and tags
+    come from a --baseline findings file. Output goes to console, and tools
+    are started through executor. banner and verbose mirror --no-banner and
+    --verbose, and run_timestamp fills {timestamp} in output_dir; scans
+    sharing it write to the same run directory.
+    """
+
+    baseline: Baseline | None = None
+    match_status: list[int] = field(default_factory=list)
+    exclude_url: list[re.Pattern[str]] = field(default_factory=list)
+    scope: re.Pattern[str] | None = None
+    known: set[str] = field(default_factory=set)
+    tags: dict[str, str] = field(default_factory=dict)
+    vhost_chain: list[str] = field(default_factory=list)
+    output_dir: str = ""
+    label: str = ""
+    summary: bool = True
+    summary_only: bool = False
+    legacy_json: bool = False
+    collapse_duplicates: bool = False
+    console: Console = field(default_factory=Console)
+    executor: Executor = exec_executor
+    banner: bool = True
+    verbose: bool = False
+    run_timestamp: str = field(default_factory=lambda: datetime.now().strftime(RUN_TIMESTAMP_FORMAT))
+
+
+async def run_cli_scan(
+    mode: str,
+    tool: str,
+    target: str,
+    wordlist: str,
+    options: dict,
+    scan: ScanSettings | None = None,
+) -> ScanResult:
+    """Run a scan in non-interactive CLI mode with Rich output.
+
+    scan settles everything beyond the tool's options. Findings matching
+    its soft-404 baseline are dropped as they arrive, as are those whose URL
+    matches an exclude_url pattern or, having a URL, does not match scope
+    (--scope-regex); the raw output keeps every line. Once the scan has
+    finished, match_status keeps only findings with those status codes, and
+    findings whose finding_key() is in known (from --baseline) are dropped,
+    so only new ones are written and reported. The rest take any tag
+    recorded for them in tags, and a tags sidecar is written beside the JSON
+    when any finding is tagged.
+
+    output_dir overrides the configured output directory; its {host} and
+    {timestamp} tokens are filled in here. legacy_json writes the findings
+    as a bare array instead of the versioned envelope. A label prefixes
+    every live output line, which keeps concurrent scans apart;
+    summary=False skips the per-scan summary so the caller can print its
+    own, and summary_only stops the tool's output lines being echoed as
+    they arrive. Vhost findings with identical responses are grouped into
+    clusters, and collapse_duplicates keeps one finding per cluster.
+    vhost_chain is recorded on every finding of a nested --vhost-recurse
+    scan.
+
+    A positive "max_output_lines" option stops the tool once that many
+    output lines have been read. Tools mostly print findings, so this caps
+    what a runaway scan reports rather than the requests it sends.
+    The "stop_on_first" option stops it at the first finding that passes
+    the live filters and would also be kept by match_status, the
+    "min_status"/"max_status" options and known; that finding is then the
+    only one kept.
+    With the "capture" option set, responses for 200 and 5xx findings are
+    fetched afterwards and stored under <output_dir>/captures/. The
+    "keep_raw" option has ffuf and feroxbuster also write their own JSON
+    output under <output_dir>/raw/. The "hash_bodies" option fetches each
+    200 finding again and groups URLs that serve identical bodies. The
+    "filter_redirect_loops" option drops findings that redirect to
+    themselves or loop back through other findings. With the "skip_empty"
+    option set, a scan that finds nothing leaves no output files behind and
+    its result's output paths are None. The "compress" option gzips the raw,
+    JSON and tool output files once they are complete, and "compact_json"
+    writes the findings JSON on one line.
+    """
+    scan = scan or ScanSettings()
+    baseline, exclude_url, scope = scan.baseline, scan.exclude_url, scan.scope
+    summary_only, vhost_chain, label = scan.summary_only, scan.vhost_chain, scan.label
+    console = scan.console
+    config = load_config()
+    colours = load_status_colours(config)
+    output_dir = str(expand_output_dir(
+        scan.output_dir or config.get("general", "output_directory", fallback="./output"),
+        sanitise_hostname(target),
+        scan.run_timestamp,
+    ))
+    raw_path, json_path = generate_output_paths(target, tool, mode, output_dir)
+
+    if options.get("resume") == "true":
+        state_dir = os.path.join(output_dir, "state", sanitise_hostname(target))
+        os.makedirs(state_dir, exist_ok=True)
+        options["state_dir"] = os.path.abspath(state_dir)
+        options["resume_from"] = latest_state_file(state_dir)
+
+    if options.get("keep_raw") == "true":
+        if tool in RAW_OUTPUT_TOOLS:
+            raw_dir = Path(output_dir) / "raw"
+            raw_dir.mkdir(parents=True, exist_ok=True)
+            options["raw_output"] = str((raw_dir / json_path.name).resolve())
+        else:
+            logger.warning("--keep-raw is not supported by %s, ignoring.", tool)
+
+    scanner = create_scanner(tool, mode, target, wordlist, options, scan.executor)
+    command = scanner.build_command()
+    logger.debug(
+        "running %s", shlex.join(mask_argv(command)),
+        extra={"fields": {"argv": mask_argv(command)}},
+    )
+
+    prefix = f"[bold]{label}[/bold] " if label else ""
+
+    if scan.banner:
+        console.print(f"\n[bold cyan]KrakenBuster[/bold cyan] - {tool} ({mode} mode)")
+    console.print(f"[dim]Target:[/dim]   {target}")
+    console.print(f"[dim]Wordlist:[/dim] {wordlist}")
+    console.print(f"[dim]Command:[/dim]  {' '.join(mask_argv(command))}\n")
+    if scan.verbose:
+        _print_command_panel(console, command, scanner.working_directory(), options, label)
+    if options.get("resume_from"):
+        console.print(f"[dim]Resuming from state file:[/dim] {options['resume_from']}\n")
+
+    result = ScanResult(
+        tool=tool,
+        mode=mode,
+        target=target,
+        wordlist=wordlist,
+    )
+
+    meta = ScanMeta.from_options(
+        tool, mode, target, wordlist, options,
+        tool_version=await tool_version(tool, scan.executor),
+    )
+    await write_raw_header(raw_path, meta)
+    result._config_path = ""
+    config_path = json_path.with_name(f"{json_path.stem}_config.json")
+    try:
+        write_scan_config(config_path, meta, options, {
+            "output_dir": output_dir,
+            "command": mask_argv(command),
+            "argv": mask_argv(sys.argv),
+        })
+    except OSError as exc:
+        logger.warning("cannot write scan config: %s", exc)
+    else:
+        result._config_path = str(config_path)
+
+    start_time = time.time()
+    scan_fields = {"tool": tool, "mode": mode, "target": target, "label": label}
+    emit_event("scan_started", **scan_fields, wordlist=wordlist, command=mask_argv(command))
+
+    process = await scanner.start(command)
+
+    assert process.stdout is not None
+    assert process.stderr is not None
+
+    try:
+        max_output_lines = int(options.get("max_output_lines", "0"))
+    except ValueError:
+        max_output_lines = 0
+    capped = False
+    stop_on_first = options.get("stop_on_first") == "true"
+    stopped = False
+    min_status = int(options.get("min_status") or 0)
+    max_status = int(options.get("max_status") or 0)
+
+    def kept_after_scan(finding: Finding) -> bool:
+        """Return True if the status and --baseline filters applied after the scan keep finding."""
+        return (
+            (not scan.match_status or bool(filter_by_status([finding], scan.match_status)))
+            and bool(filter_by_status_range([finding], min_status, max_status))
+            and finding_key(finding) not in scan.known
+        )
+
+    # Lines with text that parse_finding() could not read, and the latest one
+    unparsed = 0
+    unparsed_sample = ""
+    # Last whole percentage sent as a progress event, so ffuf's frequent
+    # redraws do not flood --json-logs
+    event_percent = -1
+
+    # Redraw progress in place only when this is the sole scan on a terminal;
+    # labelled concurrent scans print a line every 10% instead. Vhost scans
+    # add a spinner, as ffuf can go a long time between results.
+    progress = _ProgressLine(
+        console, prefix, in_place=console.is_terminal and not label, spinner=mode == "vhost"
+    )
+
+    # Findings arrive here and become result.findings once the tool exits;
+    # the threaded passes after that only set fields on existing findings
+    live = FindingStore()
+    # The text file stays open while output is read, each line flushed as
+    # it arrives, so a crashed scan still leaves a readable partial file
+    raw_out = StreamingTextWriter(raw_path)
+
+    async def read_stdout() -> None:
+        nonlocal capped, stopped, unparsed, unparsed_sample
+        # ffuf prints a multi-keyword result's inputs on the lines after it
+        last_parsed: Finding | None = None
+        # Tool colour codes are stripped: Rich measures line width on the
+        # text it is given, so stray escapes would misalign and mis-wrap output.
+        async for raw_line in process.stdout:
+            line = strip_ansi(raw_line.decode("utf-8", errors="replace")).rstrip()
+            if not line:
+                continue
+
+            result.raw_lines.append(line)
+            # Written before clearing the progress line, so the spinner cannot
+            # redraw it between the clear and the prints below.
+            await raw_out.write_line(line)
+            progress.clear()
+            if max_output_lines and len(result.raw_lines) == max_output_lines:
+                capped = True
+                console.print(
+                    f"{prefix}[yellow]Output cap of {max_output_lines:,} lines reached, "
+                    f"stopping {tool}[/yellow]"
+                )
+                try:
+                    process.terminate()
+                except ProcessLookupError:
+                    pass
+
+            keyword_input = parse_ffuf_input(line) if tool == "ffuf" else None
+            if keyword_input:
+                # The inputs belong to the last result line, which may have
+                # been filtered out below; they are then dropped with it
+                if last_parsed:
+                    keyword, value = keyword_input
+                    last_parsed.inputs[keyword] = value
+                if not summary_only:
+                    console.print(f"{prefix}[dim]{line}[/dim]")
+                continue
+
+            finding = parse_finding(line)
+            if finding:
+                last_parsed = finding
+            if finding and tool == "ffuf" and not finding.url and not finding.inputs:
+                word = parse_ffuf_word(line)
+                if word:
+                    finding.inputs["FUZZ"] = word
+            if finding and vhost_chain:
+                finding.vhost_chain = list(vhost_chain)
+            if finding and exclude_url and any(p.search(finding.url) for p in exclude_url):
+                continue
+            if finding and scope and finding.url and not scope.search(finding.url):
+                if not summary_only:
+                    console.print(f"{prefix}[dim]{line} (out of scope)[/dim]")
+                continue
+            if finding and baseline and baseline.matches(finding):
+                if not summary_only:
+                    console.print(f"{prefix}[dim]{line} (matches soft-404 baseline)[/dim]")
+                continue
+            if finding:
+                live.add(finding)
+                emit_event("finding", **scan_fields, **asdict(finding))
+                # Only a finding that will survive the post-scan filters stops the tool
+                if stop_on_first and kept_after_scan(finding):
+                    stopped = True
+                    if not summary_only:
+                        colour = status_colour(finding.status_code, colours)
+                        console.print(f"{prefix}[{colour}][{finding.status_code}][/{colour}] {line}")
+                    console.print(f"{prefix}[yellow]First finding found, stopping {tool}[/yellow]")
+                    try:
+                        process.terminate()
+                    except ProcessLookupError:
+                        pass
+                    break
+            elif not line.startswith("#") and re.search(r"[A-Za-z0-9]", line):
+                unparsed += 1
+                unparsed_sample = line
+            if summary_only:
+                continue
+            if finding:
+                status = finding.status_code
+                colour = status_colour(status, colours)
+                console.print(f"{prefix}[{colour}][{status}][/{colour}] {line}")
+            else:
+                console.print(f"{prefix}[dim]{line}[/dim]")
+
+    def handle_stderr(line: str) -> None:
+        nonlocal event_percent
+        line = strip_ansi(line).strip()
+        if not line:
+            return
+        done_total = parse_ffuf_progress(line) if tool == "ffuf" else None
+        if done_total:
+            progress.update(*done_total)
+            done, total = done_total
+            if done * 100 // total != event_percent:
+                event_percent = done * 100 // total
+                emit_event("progress", **scan_fields, done=done, total=total)
+        else:
+            result.stderr_lines.append(line)
+
+    async def read_stderr() -> None:
+        # ffuf redraws its progress line with carriage returns, so split on
+        # those as well as newlines to see each update as it arrives
+        decoder = codecs.getincrementaldecoder("utf-8")(errors="replace")
+        buffer = ""
+        while chunk := await process.stderr.read(4096):
+            buffer += decoder.decode(chunk)
+            *lines, buffer = re.split(r"[\r\n]", buffer)
+            for line in lines:
+                handle_stderr(line)
+        handle_stderr(buffer + decoder.decode(b"", final=True))
+
+    async def spin() -> None:
+        while True:
+            await asyncio.sleep(SPINNER_INTERVAL)
+            progress.tick()
+
+    progress.begin()
+    spinner = asyncio.create_task(spin()) if progress.spinner else None
+    try:
+        async with raw_out:
+            await asyncio.gather(read_stdout(), read_stderr())
+    finally:
+        if spinner:
+            spinner.cancel()
+    await process.wait()
+    progress.clear()
+    result.findings = live.snapshot()
+    # Stopping the tool at the output-line cap or first finding is expected,
+    # not a failure
+    result.failed = process.returncode != 0 and not (capped or stopped)
+    if result.failed:
+        emit_event(
+            "error", **scan_fields, message=f"{tool} exited with status {process.returncode}",
+            stderr=result.stderr_lines[-5:],
+        )
+
+    # A resumed scan that ran to completion no longer needs its state file
+    if options.get("resume_from") and process.returncode == 0:
+        try:
+            os.remove(options["resume_from"])
+        except OSError:
+            pass
+
+    # If ffuf's live output could not be parsed (an unfamiliar build, say),
+    # fall back to the JSON it wrote for --keep-raw
+    if tool == "ffuf" and options.get("raw_output") and not result.findings:
+        try:
+            recovered = parse_ffuf_json(Path(options["raw_output"]).read_text())
+        except (OSError, ValueError):
+            recovered = []
+        recovered = [
+            f for f in recovered
+            if not (exclude_url and any(p.search(f.url) for p in exclude_url))
+            and not (scope and f.url and not scope.search(f.url))
+            and not (baseline and baseline.matches(f))
+        ]
+        for finding in recovered:
+            finding.vhost_chain = list(vhost_chain or [])
+        if recovered:
+            logger.warning(
+                "no findings parsed from ffuf output, using its JSON file (%d results)",
+                len(recovered),
+            )
+            result.findings = recovered
+    if not result.findings and unparsed >= UNPARSED_WARN_LINES:
+        hint = ", or use --keep-raw to keep its JSON output" if tool in RAW_OUTPUT_TOOLS else ""
+        logger.warning(
+            "%d lines of %s output had no recognisable finding; its output format may "
+            "have changed (check the %s version%s). Last unparsed line: %s",
+            unparsed, tool, tool, hint, unparsed_sample,
+        )
+
+    result.duration_seconds = time.time() - start_time
+    if scan.match_status:
+        parsed_count = len(result.findings)
+        result.findings = filter_by_status(result.findings, scan.match_status)
+        console.print(
+            f"\n{prefix}[dim]Kept {len(result.findings)} of {parsed_count} findings "
+            f"with status {','.join(map(str, scan.match_status))}[/dim]"
+        )
+    if min_status or max_status:
+        parsed_count = len(result.findings)
+        result.findings = filter_by_status_range(result.findings, min_status, max_status)
+        console.print(
+            f"\n{prefix}[dim]Kept {len(result.findings)} of {parsed_count} findings "
+            f"with status {min_status or 100}-{max_status or 599}[/dim]"
+        )
+    if options.get("filter_redirect_loops") == "true":
+        loops = detect_redirect_loops(result.findings)
+        if loops:
+            result.findings = [f for i, f in enumerate(result.findings) if i not in loops]
+            console.print(f"\n{prefix}[dim]Dropped {len(loops)} redirect loops[/dim]")
+    # Tags are carried before the known drop, so the sidecar written below
+    # keeps the tags of unchanged findings for the next --baseline run
+    if scan.tags:
+        carried = carry_tags(result.findings, scan.tags)
+        if carried:
+            console.print(f"\n{prefix}[dim]Carried {carried} tags over from the baseline[/dim]")
+    unchanged: list[Finding] = []
+    if scan.known:
+        parsed_count = len(result.findings)
+        unchanged = [f for f in result.findings if finding_key(f) in scan.known]
+        result.findings = [f for f in result.findings if finding_key(f) not in scan.known]
+        console.print(
+            f"\n{prefix}[dim]Dropped {len(unchanged)} of {parsed_count} "
+            f"findings already in the baseline[/dim]"
+        )
+    if mode == "vhost":
+        result.clusters = cluster_vhosts(result.findings)
+        if scan.collapse_duplicates and result.clusters:
+            result.findings = collapse_clusters(result.findings, result.clusters)
+    if options.get("capture") == "true":
+        result.secrets = await _capture_findings(
+            console, result.findings, options, Path(output_dir) / "captures", prefix
+        )
+    if options.get("hash_bodies") == "true":
+        hashed = sum(1 for f in result.findings if f.url and f.status_code == 200)
+        if hashed:
+            console.print(f"\n{prefix}[dim]Hashing {hashed} response bodies...[/dim]")
+        try:
+            workers = min(int(options.get("threads", "10")), HASH_WORKERS)
+        except ValueError:
+            workers = HASH_WORKERS
+        result.duplicate_bodies = await asyncio.to_thread(
+            hash_and_group, result.findings, new_http_client(options), workers
+        )
+
+    meta.end_time = utc_timestamp()
+    result._tool_raw_path = options.get("raw_output", "")
+    if not result.findings and options.get("skip_empty") == "true":
+        # The raw output and scan config are written as the scan runs, so
+        # they are removed again rather than never created
+        for path in (raw_path, result._config_path, result._tool_raw_path):
+            if path:
+                Path(path).unlink(missing_ok=True)
+        raw_path = json_path = None
+        result._config_path = result._tool_raw_path = ""
+        console.print(f"{prefix}[dim]No findings; nothing written.[/dim]")
+    else:
+        await append_raw_line(raw_path, f"# end_time: {meta.end_time}")
+        compact = options.get("compact_json") == "true"
+        if scan.legacy_json:
+            await write_json_results(json_path, result.findings, compact)
+        else:
+            await write_envelope(json_path, result.findings, meta, result.secrets, compact)
+        if any(f.tag for f in result.findings + unchanged):
+            try:
+                write_tags_sidecar(json_path, result.findings + unchanged)
+            except OSError as exc:
+                logger.warning("cannot write tags file: %s", exc)
+        if options.get("compress") == "true":
+            raw_path, json_path, result._tool_raw_path = await asyncio.to_thread(
+                _compress_outputs, raw_path, json_path, result._tool_raw_path
+            )
+
+    # Store output paths for the summary, as the TUI does
+    result._raw_path = raw_path
+    result._json_path = json_path
+    result._meta = meta
+    emit_event(
+        "scan_finished", **scan_fields, findings=len(result.findings),
+        duration_seconds=round(result.duration_seconds, 3), failed=result.failed,
+        output=str(json_path or ""),
+    )
+
+    if scan.summary:
+        print_scan_summary(console, result)
+    return result
+
+
+def _compress_outputs(raw_path: Path, json_path: Path, tool_raw_path: str) -> tuple[Path, Path, str]:
+    """Gzip a finished scan's output files, returning their new paths.
+
+    The files are written plain while the scan runs, as the raw output is
+    read back after it and the tools write their own files, so a scan that
+    is interrupted before this point leaves them uncompressed. A file that
+    cannot be compressed is left as it is, with a warning.
+    """
+    def compress(path: Path) -> Path:
+        try:
+            return compress_file(path)
+        except OSError as exc:
+            logger.warning("cannot compress %s: %s", path, exc)
+            return path
+
+    tool_raw = tool_raw_path
+    if tool_raw_path and Path(tool_raw_path).exists():
+        tool_raw = str(compress(Path(tool_raw_path)))
+    return compress(raw_path), compress(json_path), tool_raw
+
+
+def _print_command_panel(
+    console: Console, command: list[str], cwd: str | None, options: dict, label: str = ""
+) -> None:
+    """Print the exact command and relevant environment for --verbose.
+
+    The command is shell-quoted so it can be pasted to reproduce the scan;
+    URL passwords and sensitive header values are masked.
+    """
+    lines = [
+        f"[bold]Command:[/bold] {escape(shlex.join(mask_argv(command)))}",
+        f"[bold]Directory:[/bold] {escape(cwd or os.getcwd())}",
+        f"[bold]Proxy:[/bold] {escape(mask_credentials(options.get('proxy', ''))) or 'none'}",
+    ]
+    if options.get("replay_proxy"):
+        lines.append(f"[bold]Replay proxy:[/bold] {escape(mask_credentials(options['replay_proxy']))}")
+    env = [
+        f"{name}={mask_credentials(os.environ[name])}"
+        for name in VERBOSE_ENV_VARS if name in os.environ
+    ]
+    lines.append(f"[bold]Environment:[/bold] {escape(' '.join(env)) or 'none relevant'}")
+    title = f"{label} command" if label else "Command"
+    console.print(Panel("\n".join(lines), title=title, border_style="cyan", expand=False))
+
+
+# Braille spinner drawn before in-place vhost progress, advanced every tick
+SPINNER_FRAMES = "\u280b\u2819\u2839\u2838\u283c\u2834\u2826\u2827\u2807\u280f"
+SPINNER_INTERVAL = 0.1
+
+
+class _ProgressLine:
+    """Progress indicator with percentage and ETA for a CLI scan.
+
+    With spinner set, an in-place line also gets a spinner that tick()
+    advances, so a long quiet stretch still shows the scan is alive.
+    """
+
+    def __init__(self, console: Console, prefix: str, in_place: bool, spinner: bool = False) -> None:
+        self.console = console
+        self.prefix = prefix
+        self.in_place = in_place
+        self.spinner = spinner and in_place
+        self.start = time.monotonic()
+        self.shown = False
+        self.last_step = -1
+        self.frame = 0
+        self.text = ""
+
+    def begin(self) -> None:
+        """Draw the spinner before the tool has reported any progress."""
+        if self.spinner:
+            self.text = "Waiting for progress..."
+            self._draw()
+
+    def tick(self) -> None:
+        """Advance the spinner, redrawing the line if a finding cleared it."""
+        if self.spinner and self.text:
+            self.frame = (self.frame + 1) % len(SPINNER_FRAMES)
+            self._draw()
+
+    def _draw(self) -> None:
+        frame = f"\x1b[36m{SPINNER_FRAMES[self.frame]}\x1b[0m " if self.spinner else ""
+        self.console.file.write(f"\r\x1b[2K{frame}{self.text}")
+        self.console.file.flush()
+        self.shown = True
+
+    def update(self, done: int, total: int) -> None:
+        """Show the latest progress reported by the tool."""
+        percent = done / total * 100
+        text = f"Progress: {percent:5.1f}% ({done:,}/{total:,})"
+        if 0 < done < total:
+            remaining = (time.monotonic() - self.start) / done * (total - done)
+            minutes, seconds = divmod(int(remaining), 60)
+            text += f"  ETA {minutes}m {seconds:02d}s"
+
+        if self.in_place:
+            self.text = text
+            self._draw()
+        elif int(percent // 10) > self.last_step:
+            self.last_step = int(percent // 10)
+            self.console.print(f"{self.prefix}[dim]{text}[/dim]")
+
+    def clear(self) -> None:
+        """Erase an in-place progress line so other output starts cleanly."""
+        if self.shown:
+            self.console.file.write("\r\x1b[2K")
+            self.console.file.flush()
+            self.shown = False
+
+
+async def _capture_findings(
+    console: Console, findings: list[Finding], options: dict, capture_dir: Path, prefix: str = ""
+) -> list[SecretMatch]:
+    """Capture responses for interesting findings, recording each path, encoding and latency.
+
+    With the "scan_secrets" option set, each capture is also searched for
+    likely secrets, which are returned.
+    """
+    client = new_http_client(options)
+    try:
+        max_bytes = int(options.get("capture_bytes", "4096"))
+    except ValueError:
+        max_bytes = 4096
+
+    secrets: list[SecretMatch] = []
+    targets = [f for f in findings if should_capture(f)]
+    if targets:
+        console.print(f"\n{prefix}[dim]Capturing {len(targets)} responses...[/dim]")
+    for finding in targets:
+        try:
+            path, finding.encoding, finding.latency_ms = await asyncio.to_thread(
+                capture_response, finding.url, client, max_bytes, capture_dir
+            )
+        except OSError as exc:
+            console.print(f"{prefix}[yellow]Capture failed for {finding.url}: {exc}[/yellow]")
+            continue
+        finding.capture = str(path)
+
+        if options.get("scan_secrets") == "true":
+            for match in scan_for_secrets(path.read_bytes()):
+                match.url = finding.url
+                secrets.append(match)
+    return secrets
+
+
+def print_scan_summary(console: Console, result: ScanResult) -> None:
+    """Print the findings breakdown, output files, and stderr for one scan."""
+    console.print(f"\n[bold cyan]Scan Complete[/bold cyan]")
+    console.print(f"Duration: {result.duration_formatted}")
+    console.print(f"Findings: [bold green]{len(result.findings)}[/bold green]")
+
+    if result.findings:
+        table = Table(title="Findings Breakdown")
+        table.add_column("Status Code", style="cyan", width=12)
+        table.add_column("Count", style="green", width=8)
+        table.add_column("Example URL", style="white")
+
+        for status, items in sorted(result.findings_by_status.items()):
+            inputs = ", ".join(f"{k}={v}" for k, v in items[0].inputs.items())
+            example = items[0].url or inputs or "N/A"
+            table.add_row(str(status), str(len(items)), example)
+
+        console.print(table)
+
+    for cluster in result.clusters:
+        console.print(f"[yellow]{cluster.describe()}[/yellow]")
+
+    interesting = highlight_interesting(
+        result.findings, load_interesting_keywords(load_config())
+    )
+    if interesting:
+        table = Table(title="Interesting Findings", border_style="bold yellow")
+        table.add_column("Status Code", style="cyan", width=12)
+        table.add_column("URL", style="bold yellow")
+        for finding in interesting:
+            inputs = ", ".join(f"{k}={v}" for k, v in finding.inputs.items())
+            table.add_row(str(finding.status_code), finding.url or inputs or "N/A")
+        console.print(table)
+
+    anomalies = mark_size_anomalies(result.findings)
+    if anomalies:
+        table = Table(title="Size Anomalies")
+        table.add_column("Status Code", style="cyan", width=12)
+        table.add_column("Size", style="bold magenta", justify="right")
+        table.add_column("URL", style="white")
+        for index in sorted(anomalies):
+            finding = result.findings[index]
+            table.add_row(str(finding.status_code), f"{finding.size:,}", finding.url or "N/A")
+        console.print(table)
+
+    compressed = [f for f in result.findings if f.encoding]
+    if compressed:
+        encodings = ", ".join(sorted({f.encoding for f in compressed}))
+        console.print(
+            f"[yellow]{len(compressed)} captured findings were served compressed ({encodings}); "
+            "a small size may be the compressed byte count, so check the capture[/yellow]"
+        )
+
+    slowest = slowest_findings(result.findings)
+    if slowest:
+        table = Table(title="Slowest Responses")
+        table.add_column("Status Code", style="cyan", width=12)
+        table.add_column("Time (ms)", style="bold magenta", justify="right")
+        table.add_column("URL", style="white")
+        for finding in slowest:
+            table.add_row(str(finding.status_code), f"{finding.latency_ms:,}", finding.url)
+        console.print(table)
+
+    loops = detect_redirect_loops(result.findings)
+    if loops:
+        table = Table(title="Redirect Loops")
+        table.add_column("Status Code", style="cyan", width=12)
+        table.add_column("URL", style="white")
+        table.add_column("Redirects To", style="yellow")
+        for index in sorted(loops):
+            finding = result.findings[index]
+            table.add_row(str(finding.status_code), finding.url, finding.redirect)
+        console.print(table)
+
+    if result.duplicate_bodies:
+        table = Table(title="Identical Pages")
+        table.add_column("Body SHA-256", style="cyan", width=14)
+        table.add_column("Count", style="green", justify="right")
+        table.add_column("URLs", style="white")
+        groups = sorted(result.duplicate_bodies.items(), key=lambda item: -len(item[1]))
+        for digest, urls in groups:
+            shown = ", ".join(urls[:3]) + (f" (+{len(urls) - 3} more)" if len(urls) > 3 else "")
+            table.add_row(digest[:12], str(len(urls)), shown)
+        console.print(table)
+
+    if result.secrets:
+        table = Table(title="Possible Secrets")
+        table.add_column("Type", style="yellow")
+        table.add_column("Value", style="white")
+        table.add_column("URL", style="cyan")
+        for match in result.secrets:
+            value = match.value if len(match.value) <= 48 else match.value[:45] + "..."
+            table.add_row(match.kind, value, match.url)
+        console.print(table)
+
+    if result._json_path:
+        console.print(f"\n[dim]Raw output:[/dim]  {result._raw_path}")
+        console.print(f"[dim]JSON output:[/dim] {result._json_path}")
+    if result._tool_raw_path:
+        console.print(f"[dim]Tool output:[/dim] {result._tool_raw_path}")
+    if result._config_path:
+        console.print(f"[dim]Scan config:[/dim] {result._config_path}")
+
+    if result.stderr_lines:
+        console.print("\n[bold red]Warnings/Errors:[/bold red]")
+        for line in result.stderr_lines[-10:]:
+            console.print(f"  [red]{line}[/red]")
+        summary, hint = classify_stderr(result.stderr_lines)
+        if summary:
+            console.print(Panel(hint, title=f"Likely cause: {summary}", border_style="yellow", expand=False))
+
+
+async def run_vhost_recursive(
+    tool: str,
+    target: str,
+    domain: str,
+    wordlist: str,
+    options: dict,
+    max_depth: int,
+    scan: ScanSettings | None = None,
+) -> list[tuple[str, ScanResult]]:
+    """Fuzz vhosts under domain, then under each vhost found, breadth first.
+
+    Each found word becomes a parent domain (word.parent) for the next level,
+    down to max_depth levels below domain. A host is only scanned once, and
+    findings in a duplicate cluster are not followed, as a wildcard parent
+    would otherwise make every word a child. Returns (parent domain, result)
+    pairs in scan order, starting with domain itself. Every scan uses scan's
+    settings, with its own label and vhost chain and no summary.
+    """
+    scan = scan or ScanSettings()
+    results: list[tuple[str, ScanResult]] = []
+    seen = {domain.lower()}
+    level: list[tuple[str, list[str]]] = [(domain, [])]
+    for depth in range(max_depth + 1):
+        next_level: list[tuple[str, list[str]]] = []
+        for parent, chain in level:
+            result = await run_cli_scan(
+                "vhost", tool, target, wordlist, dict(options, domain=parent),
+                replace(scan, label=parent if chain else "", summary=False, vhost_chain=chain),
+            )
+            results.append((parent, result))
+            if depth == max_depth:
+                continue
+            wildcard = {id(f) for cluster in result.clusters for f in cluster.findings}
+            for finding in result.findings:
+                word = finding.inputs.get("FUZZ")
+                if not word or id(finding) in wildcard:
+                    continue
+                child = f"{word}.{parent}".lower()
+                if child not in seen:
+                    seen.add(child)
+                    next_level.append((child, chain + [child]))
+        level = next_level
+        if not level:
+            break
+    return results
+
+
+def merge_scan_results(results: list[ScanResult]) -> ScanResult:
+    """Combine several scans into one for a single summary.
+
+    Output paths are those of the first scan.
+    """
+    first = results[0]
+    merged = ScanResult(
+        tool=first.tool,
+        mode=first.mode,
+        target=first.target,
+        wordlist=first.wordlist,
+        duration_seconds=sum(r.duration_seconds for r in results),
+        findings=[f for r in results for f in r.findings],
+        stderr_lines=[line for r in results for line in r.stderr_lines],
+        secrets=[s for r in results for s in r.secrets],
+        clusters=[c for r in results for c in r.clusters],
+        failed=any(r.failed for r in results),
+    )
+    merged._raw_path = first._raw_path
+    merged._json_path = first._json_path
+    merged._tool_raw_path = first._tool_raw_path
+    merged._config_path = first._config_path
+    return merged
+
+
+# Most running scans named in the combined status line before "+N more"
+STATUS_MAX_SCANS = 4
+
+
+def combined_status(elapsed: float, running: list[str]) -> str:
+    """Format the combined-run status line: elapsed time and scans still running."""
+    minutes, seconds = divmod(int(elapsed), 60)
+    text = f"Elapsed {minutes}m {seconds:02d}s"
+    if not running:
+        return text
+    names = ", ".join(running[:STATUS_MAX_SCANS])
+    if len(running) > STATUS_MAX_SCANS:
+        names += f" (+{len(running) - STATUS_MAX_SCANS} more)"
+    return f"{text}, running: {names}"
+
+
+async def run_combined(
+    targets: list[TargetSpec],
+    domain: str,
+    dir_tool: str | None,
+    vhost_tool: str | None,
+    wordlist: str,
+    vhost_wordlist: str,
+    dir_options: dict,
+    vhost_options: dict,
+    concurrency: int = 1,
+    target_delay: float = 0.0,
+    scan: ScanSettings | None = None,
+) -> list[HostResult]:
+    """Run directory and vhost scans against each target.
+
+    Up to `concurrency` hosts are scanned at once, each running its dir and
+    vhost scans side by side, so at most 2 * concurrency tool processes exist.
+    A slot freed by a finished host stays idle for `target_delay` seconds
+    before the next host takes it, so with a concurrency of 1 each host
+    starts that long after the previous one ends. A tool of
+    None skips that scan. A target's own domain and wordlist take precedence
+    over the shared ones; an empty vhost_wordlist means the vhost scan uses
+    the same list as the dir scan. Every scan uses scan's settings, with its
+    own label and no summary. Results are returned in target order.
+
+    When scan's console is a terminal and not quiet, a status line below
+    the scan output shows the elapsed time and which scans are still
+    running.
+    """
+    scan = scan or ScanSettings()
+    console = scan.console
+    semaphore = asyncio.Semaphore(max(concurrency, 1))
+    # Labels of the scans in progress, in start order
+    running: list[str] = []
+    # Hosts still waiting for a slot
+    waiting = len(targets)
+
+    async def tracked(label: str, scan: Awaitable[ScanResult]) -> ScanResult:
+        running.append(label)
+        try:
+            return await scan
+        finally:
+            running.remove(label)
+
+    async def scan_host(spec: TargetSpec) -> HostResult:
+        url = spec.url
+        host = urlparse(url).hostname or url
+        host_wordlist = spec.wordlist or wordlist
+        host_result = HostResult(host=host)
+        nonlocal waiting
+        async with semaphore:
+            waiting -= 1
+            start_time = time.time()
+            scans: list[tuple[str, Awaitable[ScanResult]]] = []
+            if dir_tool:
+                scans.append(("dir", run_cli_scan(
+                    "directory", dir_tool, url, host_wordlist, dict(dir_options),
+                    replace(scan, label=f"{host} dir", summary=False),
+                )))
+            if vhost_tool:
+                options = dict(vhost_options, domain=spec.domain or domain or host)
+                scans.append(("vhost", run_cli_scan(
+                    "vhost", vhost_tool, url, vhost_wordlist or host_wordlist, options,
+                    replace(scan, label=f"{host} vhost", summary=False),
+                )))
+
+            outcomes = await asyncio.gather(
+                *(tracked(f"{host} {kind}", coro) for kind, coro in scans),
+                return_exceptions=True,
+            )
+            for (kind, _), outcome in zip(scans, outcomes):
+                if isinstance(outcome, BaseException):
+                    message = str(outcome) or type(outcome).__name__
+                    setattr(host_result, f"{kind}_error", message)
+                    emit_event(
+                        "error", tool=dir_tool if kind == "dir" else vhost_tool,
+                        mode="directory" if kind == "dir" else "vhost", target=url,
+                        label=f"{host} {kind}", message=message,
+                    )
+                else:
+                    setattr(host_result, f"{kind}_result", outcome)
+            host_result.duration_seconds = time.time() - start_time
+            if target_delay > 0 and waiting:
+                # Keep the slot through the pause so the next host waits for
+                # it; asyncio.sleep is cancellable, so Ctrl+C is not held up
+                await asyncio.sleep(target_delay)
+        return host_result
+
+    if not console.is_terminal or console.quiet:
+        return list(await asyncio.gather(*(scan_host(spec) for spec in targets)))
+
+    # Rich keeps the status line below anything else printed to the
+    # console, so the scans' own output is not interleaved with it
+    start = time.monotonic()
+    with console.status(combined_status(0, running)) as status:
+        async def tick() -> None:
+            while True:
+                await asyncio.sleep(1)
+                status.update(combined_status(time.monotonic() - start, running))
+
+        ticker = asyncio.create_task(tick())
+        try:
+            return list(await asyncio.gather(*(scan_host(spec) for spec in targets)))
+        finally:
+            ticker.cancel()
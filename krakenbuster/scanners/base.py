@@ -3,9 +3,40 @@
 from __future__ import annotations
 
 import asyncio
+import shlex
+import signal
 from abc import ABC, abstractmethod
 from dataclasses import dataclass, field
-from typing import AsyncIterator
+from typing import AsyncIterator, Awaitable, Callable
+
+from krakenbuster.log import logger
+from krakenbuster.output import strip_ansi
+
+# Flags a tool accepts more than once, so extra args repeating them add to
+# the managed ones instead of conflicting
+REPEATABLE_FLAGS = {"-H", "--headers"}
+
+# Large enough for tools like dirsearch that draw progress bars with
+# carriage returns and no newlines, producing "lines" far beyond asyncio's
+# default 64KB limit.
+STREAM_LIMIT = 4 * 1024 * 1024
+
+# Starts a tool: (command, working directory) -> a running process with
+# piped stdout and stderr. Anything offering the asyncio Process attributes
+# the scanners use (stdout, stderr, wait, terminate, kill, send_signal,
+# returncode) will do, so a fake can replay canned tool output.
+Executor = Callable[[list[str], "str | None"], Awaitable[asyncio.subprocess.Process]]
+
+
+async def exec_executor(command: list[str], cwd: str | None) -> asyncio.subprocess.Process:
+    """Start command as a real subprocess; the default Executor."""
+    return await asyncio.create_subprocess_exec(
+        *command,
+        stdout=asyncio.subprocess.PIPE,
+        stderr=asyncio.subprocess.PIPE,
+        limit=STREAM_LIMIT,
+        cwd=cwd,
+    )
 
 
 @dataclass
@@ -17,7 +48,11 @@ class ScanLine:
 
 
 class BaseScanner(ABC):
-    """Abstract base class for all scanner implementations."""
+    """Abstract base class for all scanner implementations.
+
+    The tool is started through executor, exec_executor unless another is
+    given, so each scanner can be pointed at a fake of its own.
+    """
 
     def __init__(
         self,
@@ -25,11 +60,13 @@ class BaseScanner(ABC):
         target: str,
         wordlist: str,
         options: dict[str, str] | None = None,
+        executor: Executor | None = None,
     ) -> None:
         self.mode = mode
         self.target = target
         self.wordlist = wordlist
         self.options = options or {}
+        self.executor = executor or exec_executor
         self._process: asyncio.subprocess.Process | None = None
 
     @property
@@ -43,45 +80,69 @@ class BaseScanner(ABC):
         """Build the command-line arguments list."""
         ...
 
+    def working_directory(self) -> str | None:
+        """Return the directory to run the tool in, or None for the current one."""
+        return None
+
+    async def start(self, command: list[str]) -> asyncio.subprocess.Process:
+        """Start command through the scanner's executor and track the process."""
+        self._process = await self.executor(command, self.working_directory())
+        return self._process
+
     async def run_scan(self) -> AsyncIterator[ScanLine]:
         """Run the scan and yield output lines as they arrive.
 
-        Uses asyncio.create_subprocess_exec with piped stdout/stderr.
-        Reads stdout line by line without blocking the event loop.
+        Starts the tool through the scanner's executor and reads stdout line
+        by line without blocking the event loop.
         """
-        command = self.build_command()
-
-        # Use a large line buffer limit (4MB) to handle tools like dirsearch
-        # that output ANSI progress bars using carriage returns without newlines,
-        # creating extremely long "lines" that exceed asyncio's default 64KB limit.
-        self._process = await asyncio.create_subprocess_exec(
-            *command,
-            stdout=asyncio.subprocess.PIPE,
-            stderr=asyncio.subprocess.PIPE,
-            limit=4 * 1024 * 1024,
-        )
+        await self.start(self.build_command())
 
         assert self._process.stdout is not None
         assert self._process.stderr is not None
 
         # Read stdout line by line
+        # Tool colour codes are stripped: they would break status parsing and
+        # throw off width calculations wherever the line is displayed.
         async for raw_line in self._process.stdout:
-            line = raw_line.decode("utf-8", errors="replace").rstrip()
+            line = strip_ansi(raw_line.decode("utf-8", errors="replace")).rstrip()
             if line:
                 yield ScanLine(raw=line, is_stderr=False)
 
         # After stdout is done, read any remaining stderr
         stderr_data = await self._process.stderr.read()
         if stderr_data:
-            for line in stderr_data.decode("utf-8", errors="replace").splitlines():
+            for line in strip_ansi(stderr_data.decode("utf-8", errors="replace")).splitlines():
                 if line.strip():
                     yield ScanLine(raw=line.strip(), is_stderr=True)
 
         await self._process.wait()
 
+    def pause(self) -> bool:
+        """Suspend the running tool with SIGSTOP.
+
+        Returns False if there is no running process or the platform has no
+        SIGSTOP, in which case the tool keeps going.
+        """
+        return self._send_signal(getattr(signal, "SIGSTOP", None))
+
+    def resume(self) -> bool:
+        """Continue a tool suspended by pause()."""
+        return self._send_signal(getattr(signal, "SIGCONT", None))
+
+    def _send_signal(self, sig: int | None) -> bool:
+        if sig is None or not self._process or self._process.returncode is not None:
+            return False
+        try:
+            self._process.send_signal(sig)
+        except ProcessLookupError:
+            return False
+        return True
+
     async def cancel(self) -> None:
         """Cancel the running scan."""
         if self._process and self._process.returncode is None:
+            # A stopped process only acts on SIGTERM once continued
+            self.resume()
             try:
                 self._process.terminate()
                 await asyncio.sleep(0.5)
@@ -114,6 +175,32 @@ class BaseScanner(ABC):
         val = self.options.get(key, str(default)).lower()
         return val in ("true", "1", "yes", "on")
 
+    def _header_args(self, flag: str = "-H") -> list[str]:
+        """Build one flag per "Name: Value" line of the "headers" option."""
+        args: list[str] = []
+        for header in self._get_opt("headers").splitlines():
+            if header:
+                args.extend([flag, header])
+        return args
+
+    def _append_extra_args(self, cmd: list[str]) -> list[str]:
+        """Append the shell-quoted "extra_args" option to cmd and return it.
+
+        A flag that cmd already sets is still passed on, since the user asked
+        for it, but logged as a warning: which value wins is up to the tool.
+        """
+        extra = shlex.split(self._get_opt("extra_args"))
+        managed = {token for token in cmd if token.startswith("-")}
+        for token in extra:
+            flag = token.split("=", 1)[0]
+            if flag in managed and flag not in REPEATABLE_FLAGS:
+                logger.warning(
+                    "extra argument %s repeats a flag krakenbuster already passes to %s",
+                    flag, self.tool_name,
+                )
+        cmd.extend(extra)
+        return cmd
+
 
 def create_scanner(
     tool: str,
@@ -121,6 +208,7 @@ def create_scanner(
     target: str,
     wordlist: str,
     options: dict[str, str] | None = None,
+    executor: Executor | None = None,
 ) -> BaseScanner:
     """Factory function to create the appropriate scanner instance."""
     from krakenbuster.scanners.feroxbuster import FeroxbusterScanner
@@ -147,4 +235,4 @@ def create_scanner(
     if scanner_class is None:
         raise ValueError(f"Unknown tool: {tool}")
 
-    return scanner_class(mode=mode, target=target, wordlist=wordlist, options=options)
+    return scanner_class(mode=mode, target=target, wordlist=wordlist, options=options, executor=executor)
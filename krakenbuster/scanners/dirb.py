@@ -33,6 +33,8 @@ class DirbScanner(BaseScanner):
         if proxy:
             cmd.extend(["-p", proxy])
 
+        cmd.extend(self._header_args())
+
         auth = self._get_opt("auth")
         if auth:
             cmd.extend(["-u", auth])
@@ -30,6 +30,8 @@ class DirsearchScanner(BaseScanner):
         if proxy:
             cmd.extend(["--proxy", proxy])
 
+        cmd.extend(self._header_args())
+
         recursive = self._get_opt_bool("recursive", True)
         if recursive:
             cmd.append("-r")
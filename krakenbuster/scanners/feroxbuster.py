@@ -2,9 +2,20 @@
 
 from __future__ import annotations
 
+from pathlib import Path
+
 from krakenbuster.scanners.base import BaseScanner
 
 
+def latest_state_file(state_dir: str) -> str:
+    """Return the newest feroxbuster state file in a directory, or ''."""
+    directory = Path(state_dir)
+    if not directory.is_dir():
+        return ""
+    states = sorted(directory.glob("ferox-*.state"), key=lambda p: p.stat().st_mtime)
+    return str(states[-1]) if states else ""
+
+
 class FeroxbusterScanner(BaseScanner):
     """Scanner wrapper for feroxbuster."""
 
@@ -12,13 +23,30 @@ class FeroxbusterScanner(BaseScanner):
     def tool_name(self) -> str:
         return "feroxbuster"
 
+    def working_directory(self) -> str | None:
+        # feroxbuster writes its state file to the current directory when
+        # interrupted, so resumable scans run inside the state directory.
+        if self._get_opt_bool("resume", False):
+            return self._get_opt("state_dir") or None
+        return None
+
     def build_command(self) -> list[str]:
+        resume = self._get_opt_bool("resume", False)
+        resume_from = self._get_opt("resume_from")
+        if resume and resume_from:
+            return ["feroxbuster", "--resume-from", resume_from]
+
+        # Paths must survive the change of working directory when resuming
+        wordlist = str(Path(self.wordlist).resolve()) if resume else self.wordlist
+
         cmd = [
             "feroxbuster",
             "-u", self.target,
-            "-w", self.wordlist,
+            "-w", wordlist,
         ]
 
+        # feroxbuster treats depth 0 as unlimited recursion; KrakenBuster
+        # enforces its own output-line cap for that case.
         depth = self._get_opt("depth", "3")
         cmd.extend(["-d", depth])
 
@@ -36,11 +64,26 @@ class FeroxbusterScanner(BaseScanner):
         if proxy:
             cmd.extend(["-p", proxy])
 
+        cmd.extend(self._header_args())
+
+        insecure = self._get_opt_bool("insecure", False)
+        if insecure:
+            cmd.append("-k")
+
         status_codes = self._get_opt("status_codes", "200,204,301,302,307,401,403")
         if status_codes:
             cmd.extend(["-s", status_codes])
 
-        # Disable interactive mode for piped output
-        cmd.append("--no-state")
+        filter_similar_to = self._get_opt("filter_similar_to")
+        if filter_similar_to:
+            cmd.extend(["--filter-similar-to", filter_similar_to])
+
+        raw_output = self._get_opt("raw_output")
+        if raw_output:
+            cmd.extend(["-o", raw_output, "--json"])
+
+        # Disable state files unless the scan should be resumable
+        if not resume:
+            cmd.append("--no-state")
 
-        return cmd
+        return self._append_extra_args(cmd)
@@ -13,10 +13,62 @@ class FfufScanner(BaseScanner):
         return "ffuf"
 
     def build_command(self) -> list[str]:
+        if self._get_opt("request_file"):
+            return self._build_request_command()
         if self.mode == "vhost":
             return self._build_vhost_command()
         return self._build_dir_command()
 
+    def _build_request_command(self) -> list[str]:
+        # ffuf takes the URL, Host and headers from the raw request, so only
+        # the wordlist and tuning flags are added. The scheme comes from the
+        # target, as the request file does not carry one.
+        proto = "http" if self.target.lower().startswith("http://") else "https"
+        cmd = [
+            "ffuf",
+            "-request", self._get_opt("request_file"),
+            "-request-proto", proto,
+            "-w", self.wordlist,
+        ]
+        cmd.extend(self._keyword_wordlist_args())
+
+        threads = self._get_opt("threads", "50")
+        cmd.extend(["-t", threads])
+
+        rate_limit = self._get_opt("rate_limit", "200")
+        cmd.extend(["-rate", rate_limit])
+
+        proxy = self._get_opt("proxy")
+        if proxy:
+            cmd.extend(["-x", proxy])
+
+        replay_proxy = self._get_opt("replay_proxy")
+        if replay_proxy:
+            cmd.extend(["-replay-proxy", replay_proxy])
+
+        if self._get_opt_bool("http2", False):
+            cmd.append("-http2")
+
+        filter_codes = self._get_opt("filter_codes", "400,404")
+        if filter_codes:
+            cmd.extend(["-fc", filter_codes])
+
+        filter_size = self._get_opt("filter_size")
+        if filter_size:
+            cmd.extend(["-fs", filter_size])
+
+        filter_words = self._get_opt("filter_words")
+        if filter_words:
+            cmd.extend(["-fw", filter_words])
+
+        raw_output = self._get_opt("raw_output")
+        if raw_output:
+            cmd.extend(["-o", raw_output, "-of", "json"])
+
+        cmd.extend(["-c"])
+
+        return self._append_extra_args(cmd)
+
     def _build_dir_command(self) -> list[str]:
         # Ensure target URL ends with /FUZZ
         target = self.target.rstrip("/")
@@ -28,6 +80,7 @@ class FfufScanner(BaseScanner):
             "-u", target,
             "-w", self.wordlist,
         ]
+        cmd.extend(self._keyword_wordlist_args())
 
         extensions = self._get_opt("extensions", "php,html,txt,js")
         if extensions:
@@ -45,6 +98,15 @@ class FfufScanner(BaseScanner):
         if proxy:
             cmd.extend(["-x", proxy])
 
+        replay_proxy = self._get_opt("replay_proxy")
+        if replay_proxy:
+            cmd.extend(["-replay-proxy", replay_proxy])
+
+        cmd.extend(self._header_args())
+
+        if self._get_opt_bool("http2", False):
+            cmd.append("-http2")
+
         filter_codes = self._get_opt("filter_codes", "400,404")
         if filter_codes:
             cmd.extend(["-fc", filter_codes])
@@ -53,10 +115,14 @@ class FfufScanner(BaseScanner):
         if filter_size:
             cmd.extend(["-fs", filter_size])
 
+        raw_output = self._get_opt("raw_output")
+        if raw_output:
+            cmd.extend(["-o", raw_output, "-of", "json"])
+
         # Colourised output
         cmd.extend(["-c"])
 
-        return cmd
+        return self._append_extra_args(cmd)
 
     def _build_vhost_command(self) -> list[str]:
         domain = self._get_opt("domain", "")
@@ -68,6 +134,7 @@ class FfufScanner(BaseScanner):
             "-w", self.wordlist,
             "-H", f"Host: FUZZ.{domain}",
         ]
+        cmd.extend(self._keyword_wordlist_args())
 
         threads = self._get_opt("threads", "50")
         cmd.extend(["-t", threads])
@@ -79,6 +146,15 @@ class FfufScanner(BaseScanner):
         if proxy:
             cmd.extend(["-x", proxy])
 
+        replay_proxy = self._get_opt("replay_proxy")
+        if replay_proxy:
+            cmd.extend(["-replay-proxy", replay_proxy])
+
+        cmd.extend(self._header_args())
+
+        if self._get_opt_bool("http2", False):
+            cmd.append("-http2")
+
         filter_codes = self._get_opt("filter_codes", "400,404")
         if filter_codes:
             cmd.extend(["-fc", filter_codes])
@@ -87,6 +163,25 @@ class FfufScanner(BaseScanner):
         if filter_size:
             cmd.extend(["-fs", filter_size])
 
+        filter_words = self._get_opt("filter_words")
+        if filter_words:
+            cmd.extend(["-fw", filter_words])
+
+        raw_output = self._get_opt("raw_output")
+        if raw_output:
+            cmd.extend(["-o", raw_output, "-of", "json"])
+
         cmd.extend(["-c"])
 
-        return cmd
+        return self._append_extra_args(cmd)
+
+    def _keyword_wordlist_args(self) -> list[str]:
+        """Build -w path:KEYWORD pairs for extra wordlists, plus the -mode flag."""
+        args: list[str] = []
+        for pair in self._get_opt("wordlist_keywords").splitlines():
+            if pair:
+                args.extend(["-w", pair])
+        mode = self._get_opt("ffuf_mode")
+        if args and mode:
+            args.extend(["-mode", mode])
+        return args
@@ -41,6 +41,12 @@ class GobusterScanner(BaseScanner):
         if proxy:
             cmd.extend(["--proxy", proxy])
 
+        cmd.extend(self._header_args())
+
+        insecure = self._get_opt_bool("insecure", False)
+        if insecure:
+            cmd.append("-k")
+
         follow_redirects = self._get_opt_bool("follow_redirects", True)
         if follow_redirects:
             cmd.append("-r")
@@ -68,6 +74,12 @@ class GobusterScanner(BaseScanner):
         if proxy:
             cmd.extend(["--proxy", proxy])
 
+        cmd.extend(self._header_args())
+
+        insecure = self._get_opt_bool("insecure", False)
+        if insecure:
+            cmd.append("-k")
+
         append_domain = self._get_opt_bool("append_domain", True)
         if append_domain:
             cmd.append("--append-domain")
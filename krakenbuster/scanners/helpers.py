@@ -0,0 +1,393 @@
+"""Helpers for preparing scanner options from user-supplied flags and files."""
+
+from __future__ import annotations
+
+import asyncio
+import hashlib
+import hmac
+import ipaddress
+import random
+import re
+import shutil
+import sys
+from typing import Callable
+from urllib.parse import urlparse
+
+from krakenbuster.scanners.base import Executor, exec_executor
+
+# Arguments that make each tool print its version
+VERSION_ARGS = {
+    "feroxbuster": ["--version"],
+    "ffuf": ["-V"],
+    "gobuster": ["version"],
+    "wfuzz": ["--version"],
+    "dirsearch": ["--version"],
+    "amass": ["-version"],
+    "subfinder": ["-version"],
+}
+
+# Seconds tool_version() waits for a tool to print its version
+VERSION_TIMEOUT = 5
+
+# Package managers tried in order, with their install command and the tools
+# they package under the tool's own name. apt is Kali's, where every tool
+# but subfinder is packaged.
+PACKAGE_MANAGERS: list[tuple[str, str, set[str]]] = [
+    ("brew", "brew install {}", {"feroxbuster", "ffuf", "gobuster", "amass", "subfinder"}),
+    ("apt", "sudo apt install {}", {
+        "feroxbuster", "ffuf", "gobuster", "dirb", "wfuzz", "dirsearch", "amass",
+    }),
+    ("dnf", "sudo dnf install {}", set()),
+    ("pacman", "sudo pacman -S {}", {"feroxbuster", "gobuster"}),
+]
+
+# Install commands that work without a packaged version
+SOURCE_INSTALLS = {
+    "feroxbuster": "cargo install feroxbuster",
+    "ffuf": "go install github.com/ffuf/ffuf/v2@latest",
+    "gobuster": "go install github.com/OJ/gobuster/v3@latest",
+    "dirb": "install dirb from https://dirb.sourceforge.net/",
+    "wfuzz": "pip install wfuzz",
+    "dirsearch": "pip install dirsearch",
+    "amass": "go install -v github.com/owasp-amass/amass/v4/...@master",
+    "subfinder": "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest",
+}
+
+
+def install_hint(
+    tool: str,
+    platform: str = sys.platform,
+    which: Callable[[str], str | None] = shutil.which,
+) -> str:
+    """Return the command most likely to install tool on this system.
+
+    On macOS only Homebrew is considered; elsewhere the first of apt, dnf
+    and pacman found by which is used. When the manager has no package for
+    the tool, or none is found, the tool's SOURCE_INSTALLS entry is given.
+    """
+    for manager, command, packaged in PACKAGE_MANAGERS:
+        if (manager == "brew") != (platform == "darwin") or not which(manager):
+            continue
+        if tool in packaged:
+            return command.format(tool)
+        break
+    return SOURCE_INSTALLS.get(tool, f"install {tool} and make sure it is on PATH")
+
+
+# Current desktop and mobile browser User-Agents for --random-agent
+USER_AGENTS = [
+    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) "
+    "Chrome/124.0.0.0 Safari/537.36",
+    "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) "
+    "Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51",
+    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) "
+    "Version/17.4.1 Safari/605.1.15",
+    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) "
+    "Chrome/124.0.0.0 Safari/537.36",
+    "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) "
+    "Chrome/124.0.0.0 Safari/537.36",
+    "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+    "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4_1 like Mac OS X) AppleWebKit/605.1.15 "
+    "(KHTML, like Gecko) Version/17.4.1 Mobile/15E148 Safari/604.1",
+    "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) "
+    "Chrome/124.0.6367.82 Mobile Safari/537.36",
+]
+
+
+def random_user_agent(source: random.Random) -> str:
+    """Pick a User-Agent from USER_AGENTS using source."""
+    return source.choice(USER_AGENTS)
+
+
+def normalise_extensions(*values: str) -> str:
+    """Merge comma- or newline-separated extension lists into one comma list.
+
+    Leading dots are stripped and duplicates dropped, keeping first-seen order.
+    """
+    seen: list[str] = []
+    for value in values:
+        for part in re.split(r"[,\n]", value):
+            ext = part.strip().lstrip(".")
+            if ext and ext not in seen:
+                seen.append(ext)
+    return ",".join(seen)
+
+
+def parse_status_codes(value: str) -> list[int]:
+    """Parse a comma-separated list of HTTP status codes.
+
+    Raises ValueError if any entry is not a code between 100 and 599.
+    """
+    codes: list[int] = []
+    for part in value.split(","):
+        part = part.strip()
+        if not part:
+            continue
+        if not part.isdigit() or not 100 <= int(part) <= 599:
+            raise ValueError(f"invalid status code: {part!r}")
+        codes.append(int(part))
+    return codes
+
+
+def parse_duration(value: str) -> float:
+    """Parse a duration such as "500ms", "5s", "2m", "1h" or "3" into seconds.
+
+    Raises ValueError for malformed or negative values.
+    """
+    match = re.fullmatch(r"\s*(\d+(?:\.\d+)?)\s*(ms|s|m|h)?\s*", value)
+    if not match:
+        raise ValueError(f"invalid duration: {value!r}")
+    multiplier = {"ms": 0.001, "s": 1, "m": 60, "h": 3600}[match.group(2) or "s"]
+    return float(match.group(1)) * multiplier
+
+
+def check_header(value: str) -> str:
+    """Return a "Name: Value" header with surrounding whitespace trimmed.
+
+    Raises ValueError if there is no colon or the name is empty.
+    """
+    name, colon, _ = value.partition(":")
+    if not colon or not name.strip():
+        raise ValueError(f"expected 'Name: Value', got {value!r}")
+    return value.strip()
+
+
+def signed_header(key: str, payload: str, name: str = "X-Signature") -> str:
+    """Return a "Name: signature" header holding the hex HMAC-SHA256 of payload under key.
+
+    The tools cannot sign each request, so the header is computed once and
+    sent unchanged; it only suits APIs that sign something fixed, such as
+    the target URL.
+    """
+    digest = hmac.new(key.encode(), payload.encode(), hashlib.sha256).hexdigest()
+    return f"{name}: {digest}"
+
+
+def load_headers(path: str) -> list[str]:
+    """Read "Name: Value" headers from a file, one per line.
+
+    Blank lines and # comments are skipped. Raises OSError if the file
+    cannot be read and ValueError, naming the line, for a malformed header.
+    """
+    headers: list[str] = []
+    with open(path, "r", errors="ignore") as fh:
+        for number, line in enumerate(fh, 1):
+            line = line.strip()
+            if not line or line.startswith("#"):
+                continue
+            try:
+                headers.append(check_header(line))
+            except ValueError as exc:
+                raise ValueError(f"line {number}: {exc}") from None
+    return headers
+
+
+def check_request_file(path: str) -> None:
+    """Check a raw HTTP request template for ffuf's -request contains FUZZ.
+
+    Raises OSError if the file cannot be read and ValueError if it has no
+    FUZZ marker.
+    """
+    with open(path, "r", errors="ignore") as fh:
+        if "FUZZ" not in fh.read():
+            raise ValueError("no FUZZ marker in the request")
+
+
+def request_file_host(path: str) -> str:
+    """Return the Host header of a raw HTTP request file, or '' if it has none.
+
+    Raises OSError if the file cannot be read.
+    """
+    with open(path, "r", errors="ignore") as fh:
+        for line in fh.read().splitlines()[1:]:
+            if not line.strip():
+                break  # end of the headers
+            name, _, value = line.partition(":")
+            if name.strip().lower() == "host":
+                return value.strip()
+    return ""
+
+
+# Ports assumed to speak TLS when a bare vhost target gives no scheme
+TLS_PORTS = (443, 8443)
+
+
+def bracket_ipv6(target: str) -> str:
+    """Wrap a bare IPv6 address such as ::1 in brackets, as URLs need.
+
+    Anything else, including an already bracketed address, is returned as is.
+    """
+    try:
+        address = ipaddress.ip_address(target)
+    except ValueError:
+        return target
+    return f"[{target}]" if address.version == 6 else target
+
+
+def default_scheme(target: str) -> str:
+    """Prefix a bare host or host:port (such as 10.0.0.5:8443) with a scheme.
+
+    https is used for the ports in TLS_PORTS and http otherwise, without
+    contacting the target. A bare IPv6 address is bracketed first; one with
+    a port must already be bracketed ([::1]:8443). Raises ValueError if
+    target already has a scheme, has a path, or its port is not a number.
+    """
+    if "://" in target:
+        raise ValueError(f"target already has a scheme: {target!r}")
+    target = bracket_ipv6(target)
+    parsed = urlparse(f"//{target}")
+    if not parsed.hostname or parsed.path or parsed.query:
+        raise ValueError(f"expected host or host:port, got {target!r}")
+    try:
+        port = parsed.port
+    except ValueError:
+        raise ValueError(f"invalid port in {target!r}") from None
+    scheme = "https" if port in TLS_PORTS else "http"
+    return f"{scheme}://{target}"
+
+
+# Most addresses a --target-cidr network may hold, so a mistyped prefix such as /8
+# does not queue millions of scans
+MAX_CIDR_HOSTS = 4096
+
+
+def expand_cidr(cidr: str, scheme: str = "http", port: int = 0) -> list[str]:
+    """Return a URL for each usable host address in cidr.
+
+    The network and broadcast addresses are skipped, except in /31 and /32
+    (or IPv6 /127 and /128) networks, where every address is a host. port
+    is added unless it is 0. Raises ValueError for a malformed network or
+    one with more than MAX_CIDR_HOSTS addresses.
+    """
+    network = ipaddress.ip_network(cidr.strip(), strict=False)
+    if network.num_addresses > MAX_CIDR_HOSTS:
+        raise ValueError(
+            f"{network} is too large ({network.num_addresses:,} addresses, "
+            f"at most {MAX_CIDR_HOSTS:,} allowed)"
+        )
+    urls = []
+    for address in network.hosts():
+        host = f"[{address}]" if address.version == 6 else str(address)
+        urls.append(f"{scheme}://{host}:{port}" if port else f"{scheme}://{host}")
+    return urls
+
+
+def check_rate_threads(threads: int, rate: int) -> str:
+    """Return a warning if threads far outnumber the rate limit, else "".
+
+    The rate is shared by all threads, so with more than twice as many
+    threads as requests per second most of them only wait. A rate of 0 or
+    less means no limit and is not checked.
+    """
+    if rate > 0 and threads > 2 * rate:
+        return (
+            f"{threads} threads share a limit of {rate} requests per second, so most of "
+            f"them sit idle; --threads {rate} gives the same speed with fewer connections"
+        )
+    return ""
+
+
+# Failure signatures in tool stderr, most specific first, with a summary
+# and a remediation hint for each
+STDERR_SIGNATURES: list[tuple[re.Pattern[str], str, str]] = [
+    (re.compile(
+        r"certificate verify failed|x509: certificate|unknown authority|self[- ]signed certificate",
+        re.I,
+    ), "TLS certificate not trusted",
+     "The target's certificate is self-signed or from an unknown CA; add --insecure (-k) "
+     "if that is expected."),
+    (re.compile(
+        r"wrong version number|does not look like a TLS handshake|handshake failure"
+        r"|tls: handshake|ssl handshake",
+        re.I,
+    ), "TLS handshake failed",
+     "Check the scheme: the port may speak plain http rather than https, or only old TLS "
+     "versions."),
+    (re.compile(r"connection refused", re.I), "Connection refused",
+     "Check the target is up and listening on that port."),
+    (re.compile(r"no route to host|network is unreachable", re.I), "Target unreachable",
+     "Check the target address and your network, VPN or proxy connection."),
+    (re.compile(r"no such host|name or service not known|could not resolve|name resolution", re.I),
+     "Host name not found",
+     "Check the host name, or add it to /etc/hosts if it only resolves internally."),
+    (re.compile(r"i/o timeout|timed out|deadline exceeded", re.I), "Connection timed out",
+     "Check the target is reachable; a firewall may be dropping traffic, or try a lower --rate."),
+]
+
+
+def classify_stderr(lines: list[str]) -> tuple[str, str]:
+    """Match tool stderr against STDERR_SIGNATURES.
+
+    Returns the summary and remediation hint of the first signature found
+    in any line, or ("", "") when none matches.
+    """
+    text = "\n".join(lines)
+    for pattern, summary, hint in STDERR_SIGNATURES:
+        if pattern.search(text):
+            return summary, hint
+    return "", ""
+
+
+def check_vhost_config(target: str, domain: str) -> str:
+    """Return a warning if a vhost scan's target and domain look mismatched, else "".
+
+    A target that is itself a subdomain of domain (app.example.com for
+    example.com) is usually a slip: FUZZ.example.com is then sent to
+    whichever server hosts app.example.com. A target equal to the domain is
+    the normal setup and is not flagged.
+    """
+    host = (urlparse(target if "://" in target else f"//{target}").hostname or "").lower()
+    domain = domain.lower().strip(".")
+    try:
+        ipaddress.ip_address(domain.strip("[]"))
+    except ValueError:
+        pass
+    else:
+        return (
+            f"domain {domain} is an IP address; vhost fuzzing sends FUZZ.{domain} as the "
+            "Host header, so give the DNS name the server expects with --domain"
+        )
+    if host and domain and host.endswith(f".{domain}"):
+        return (
+            f"target host {host} is already a subdomain of {domain}; vhost fuzzing sends "
+            f"FUZZ.{domain} to its server and may not behave as expected"
+        )
+    return ""
+
+
+def load_extensions(path: str) -> str:
+    """Read extensions from a file and return a normalised comma list."""
+    with open(path, "r", errors="ignore") as fh:
+        return normalise_extensions(fh.read())
+
+
+async def tool_version(tool: str, executor: Executor = exec_executor) -> str:
+    """Return the first line of a tool's version output, or '' if unknown.
+
+    The tool is started through executor, like the scan itself, so a fake
+    executor answers this probe too. A tool still running after
+    VERSION_TIMEOUT seconds is killed.
+    """
+    args = VERSION_ARGS.get(tool)
+    if not args:
+        return ""
+    try:
+        process = await executor([tool, *args], None)
+    except OSError:
+        return ""
+    try:
+        stdout, stderr = await asyncio.wait_for(
+            asyncio.gather(process.stdout.read(), process.stderr.read()), VERSION_TIMEOUT
+        )
+        await asyncio.wait_for(process.wait(), VERSION_TIMEOUT)
+    except asyncio.TimeoutError:
+        try:
+            process.kill()
+        except ProcessLookupError:
+            pass
+        return ""
+    for line in (stdout + stderr).decode("utf-8", errors="replace").splitlines():
+        if line.strip():
+            return line.strip()
+    return ""
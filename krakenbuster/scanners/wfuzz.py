@@ -67,6 +67,8 @@ class WfuzzScanner(BaseScanner):
             if proxy:
                 cmd.extend(["-p", proxy])
 
+        cmd.extend(self._header_args())
+
         # Colourised output
         cmd.extend(["-c"])
 
@@ -101,6 +103,8 @@ class WfuzzScanner(BaseScanner):
         if proxy:
             cmd.extend(["-p", proxy])
 
+        cmd.extend(self._header_args())
+
         cmd.extend(["-c"])
 
         return cmd
@@ -8,6 +8,7 @@ import time
 from collections import deque
 from pathlib import Path
 
+from textual import events
 from textual.app import ComposeResult
 from textual.binding import Binding
 from textual.containers import Horizontal, Vertical
@@ -24,21 +25,33 @@ from textual.widgets import (
 
 from krakenbuster.output import (
     Finding,
+    FindingStore,
+    ScanMeta,
     ScanResult,
     append_raw_line,
+    expand_output_dir,
     generate_output_paths,
     parse_finding,
     parse_progress,
     parse_dirb_downloaded,
-    write_json_results,
+    sanitise_hostname,
+    status_colour,
+    truncate_url,
+    utc_timestamp,
+    write_envelope,
+    write_raw_header,
 )
 from krakenbuster.scanners.base import ScanLine, create_scanner
+from krakenbuster.scanners.helpers import tool_version
 from krakenbuster.wordlist import count_lines
 
 # Tools that output a line per request (line count is a good progress proxy)
 _VERBOSE_TOOLS = {"feroxbuster"}
 # Tools that only output findings (need progress parsing or estimation)
 _SPARSE_TOOLS = {"dirb", "gobuster", "ffuf", "wfuzz", "dirsearch"}
+# Below this many columns the findings and raw output are stacked rather
+# than side by side, as the 40% left column cannot fit its tables
+STACKED_WIDTH = 100
 
 
 class ScanOutputLine(Message):
@@ -64,6 +77,7 @@ class ScanningScreen(Screen):
     BINDINGS = [
         Binding("ctrl+c", "cancel_scan", "Cancel scan"),
         Binding("q", "cancel_scan", "Cancel"),
+        Binding("p", "toggle_pause", "Pause/Resume"),
     ]
 
     _start_time: float = 0.0
@@ -73,7 +87,7 @@ class ScanningScreen(Screen):
     _progress_from_tool: bool = False  # True if tool reported its own progress
     _configured_rate: int = 200  # configured rate limit for estimation
     _errors: int = 0
-    _findings: list[Finding] = []
+    _findings: FindingStore = FindingStore()
     _raw_lines: list[str] = []
     _stderr_lines: list[str] = []
     _rate_samples: deque = deque(maxlen=50)
@@ -84,13 +98,18 @@ class ScanningScreen(Screen):
     _json_path: Path | None = None
     _vhost_raw_path: Path | None = None
     _vhost_json_path: Path | None = None
-    _vhost_findings: list[Finding] = []
+    _meta: ScanMeta | None = None
+    _vhost_meta: ScanMeta | None = None
+    _vhost_findings: FindingStore = FindingStore()
     _vhost_lines_received: int = 0
     _vhost_raw_lines: list[str] = []
     _vhost_stderr_lines: list[str] = []
     _completed_scanners: int = 0
     _total_scanners: int = 1
     _tool_name: str = ""
+    _status_colours: dict[str, str] = {}
+    _paused_since: float | None = None  # set while the tools are suspended
+    _paused_total: float = 0.0  # seconds spent paused, excluded from rates
 
     def compose(self) -> ComposeResult:
         scan_type = getattr(self.app, "scan_type", "directory")
@@ -131,7 +150,7 @@ class ScanningScreen(Screen):
                         yield DataTable(id="findings-table")
 
                     # Vhost progress and findings (combined mode)
-                    if scan_type == "combined":
+                    if self._runs_vhost():
                         with Vertical(id="vhost-progress-panel"):
                             yield ProgressBar(
                                 total=100,
@@ -149,32 +168,45 @@ class ScanningScreen(Screen):
                         markup=True,
                         id="raw-output",
                     )
-                    if scan_type == "combined":
+                    if self._runs_vhost():
                         yield RichLog(
                             highlight=True,
                             markup=True,
                             id="vhost-raw-output",
                         )
 
+    def on_resize(self, event: events.Resize) -> None:
+        """Reflow the columns when the terminal is resized mid-scan."""
+        self._apply_layout(event.size.width)
+
+    def _apply_layout(self, width: int) -> None:
+        self.query_one("#scanning-main", Horizontal).set_class(width < STACKED_WIDTH, "stacked")
+
     def on_mount(self) -> None:
         """Initialise state and start the scan."""
+        self._apply_layout(self.app.size.width)
         self._start_time = time.time()
+        self._paused_since = None
+        self._paused_total = 0.0
         self._lines_received = 0
         self._requests_estimated = 0
         self._progress_from_tool = False
         self._errors = 0
-        self._findings = []
+        self._findings = FindingStore()
         self._raw_lines = []
         self._stderr_lines = []
         self._rate_samples = deque(maxlen=50)
         self._scan_tasks = []
         self._completed_scanners = 0
-        self._vhost_findings = []
+        self._vhost_findings = FindingStore()
         self._vhost_lines_received = 0
         self._vhost_raw_lines = []
         self._vhost_stderr_lines = []
         self._tool_name = getattr(self.app, "selected_tool", "")
 
+        from krakenbuster.config import load_config, load_status_colours
+        self._status_colours = load_status_colours(load_config())
+
         # Read configured rate limit for estimation fallback
         options = getattr(self.app, "scan_options", {})
         try:
@@ -187,8 +219,7 @@ class ScanningScreen(Screen):
         table.add_columns("Status", "Size", "Words", "Lines", "URL")
         table.fixed_columns = 5
 
-        scan_type = getattr(self.app, "scan_type", "directory")
-        if scan_type == "combined":
+        if self._runs_vhost():
             self._total_scanners = 2
             try:
                 vhost_table = self.query_one("#vhost-findings-table", DataTable)
@@ -203,6 +234,17 @@ class ScanningScreen(Screen):
         # Start the scan
         self.run_worker(self._start_scan())
 
+    def _runs_vhost(self) -> bool:
+        """Return True if a combined scan has an installed vhost tool to run.
+
+        When it does not, the screen falls back to the single-scan layout
+        instead of showing an empty vhost panel.
+        """
+        if getattr(self.app, "scan_type", "directory") != "combined":
+            return False
+        vhost_tool = getattr(self.app, "selected_vhost_tool", "ffuf")
+        return getattr(self.app, "available_tools", {}).get(vhost_tool, False)
+
     async def _start_scan(self) -> None:
         """Start the scanning process."""
         app = self.app
@@ -225,12 +267,27 @@ class ScanningScreen(Screen):
                 pass
 
         # Generate output paths
-        from krakenbuster.config import load_config
+        from krakenbuster.config import load_config, load_extra_args
         config = load_config()
-        output_dir = config.get("general", "output_directory", fallback="./output")
-        self._raw_path, self._json_path = generate_output_paths(
-            target, tool, effective_mode, output_dir
+        options = {**options, "extra_args": load_extra_args(config, tool)}
+        try:
+            output_dir = str(expand_output_dir(
+                config.get("general", "output_directory", fallback="./output"),
+                sanitise_hostname(target),
+            ))
+            self._raw_path, self._json_path = generate_output_paths(
+                target, tool, effective_mode, output_dir
+            )
+        except OSError as exc:
+            # Nothing has run yet, so go back rather than scan without output
+            self.notify(str(exc), severity="error", timeout=10)
+            self.app.pop_screen()
+            return
+        self._meta = ScanMeta.from_options(
+            tool, effective_mode, target, wordlist, options,
+            tool_version=await tool_version(tool),
         )
+        await write_raw_header(self._raw_path, self._meta)
 
         # Create and run primary scanner
         self._scanner = create_scanner(tool, effective_mode, target, wordlist, options)
@@ -240,12 +297,20 @@ class ScanningScreen(Screen):
         self._scan_tasks.append(primary_task)
 
         # Combined mode: also run vhost scanner
-        if scan_type == "combined":
+        if self._runs_vhost():
             vhost_tool = getattr(app, "selected_vhost_tool", "ffuf")
-            vhost_options = getattr(app, "vhost_options", {})
+            vhost_options = {
+                **getattr(app, "vhost_options", {}),
+                "extra_args": load_extra_args(config, vhost_tool),
+            }
             self._vhost_raw_path, self._vhost_json_path = generate_output_paths(
                 target, vhost_tool, "vhost", output_dir
             )
+            self._vhost_meta = ScanMeta.from_options(
+                vhost_tool, "vhost", target, wordlist, vhost_options,
+                tool_version=await tool_version(vhost_tool),
+            )
+            await write_raw_header(self._vhost_raw_path, self._vhost_meta)
             self._vhost_scanner = create_scanner(
                 vhost_tool, "vhost", target, wordlist, vhost_options
             )
@@ -353,10 +418,10 @@ class ScanningScreen(Screen):
         finding = parse_finding(line.raw)
         if finding:
             if scanner_id == "vhost":
-                self._vhost_findings.append(finding)
+                self._vhost_findings.add(finding)
                 self._update_vhost_findings_table(finding)
             else:
-                self._findings.append(finding)
+                self._findings.add(finding)
                 self._update_findings_table(finding)
 
         # Update testing label with current word
@@ -379,10 +444,17 @@ class ScanningScreen(Screen):
 
     async def _finalise(self) -> None:
         """Write final output files and navigate to summary."""
-        if self._json_path:
-            await write_json_results(self._json_path, self._findings)
-        if self._vhost_json_path:
-            await write_json_results(self._vhost_json_path, self._vhost_findings)
+        end_time = utc_timestamp()
+        if self._raw_path and self._json_path and self._meta:
+            self._meta.end_time = end_time
+            await append_raw_line(self._raw_path, f"# end_time: {end_time}")
+            await write_envelope(self._json_path, self._findings.snapshot(), self._meta)
+        if self._vhost_raw_path and self._vhost_json_path and self._vhost_meta:
+            self._vhost_meta.end_time = end_time
+            await append_raw_line(self._vhost_raw_path, f"# end_time: {end_time}")
+            await write_envelope(
+                self._vhost_json_path, self._vhost_findings.snapshot(), self._vhost_meta
+            )
 
         duration = time.time() - self._start_time
 
@@ -393,7 +465,7 @@ class ScanningScreen(Screen):
             wordlist=getattr(self.app, "wordlist_path", ""),
             total_words=self._total_words,
             duration_seconds=duration,
-            findings=self._findings + self._vhost_findings,
+            findings=self._findings.snapshot() + self._vhost_findings.snapshot(),
             raw_lines=self._raw_lines,
             stderr_lines=self._stderr_lines + self._vhost_stderr_lines,
             errors=self._errors,
@@ -416,7 +488,7 @@ class ScanningScreen(Screen):
                 str(finding.size),
                 str(finding.words),
                 str(finding.lines),
-                finding.url or "N/A",
+                truncate_url(finding.url) if finding.url else "N/A",
             )
             count_label = self.query_one("#findings-count", Label)
             count_label.update(f"{len(self._findings)} findings so far")
@@ -432,7 +504,7 @@ class ScanningScreen(Screen):
                 str(finding.size),
                 str(finding.words),
                 str(finding.lines),
-                finding.url or "N/A",
+                truncate_url(finding.url) if finding.url else "N/A",
             )
             count_label = self.query_one("#vhost-findings-count", Label)
             count_label.update(f"{len(self._vhost_findings)} vhost findings")
@@ -458,7 +530,7 @@ class ScanningScreen(Screen):
 
     def _refresh_stats(self) -> None:
         """Update the top bar and progress stats every second."""
-        elapsed = time.time() - self._start_time
+        elapsed = self._active_elapsed()
         elapsed_str = self._format_elapsed(elapsed)
 
         # Estimate requests completed (may be None if unknown)
@@ -482,6 +554,7 @@ class ScanningScreen(Screen):
             top_bar.update(
                 f"[bold]{tool}[/bold] | {target} | {wordlist} | "
                 f"Elapsed: {elapsed_str} | {rate_str}"
+                + (" | [bold yellow]PAUSED[/bold yellow]" if self._paused_since else "")
             )
         except Exception:
             pass
@@ -557,17 +630,32 @@ class ScanningScreen(Screen):
 
     def _status_colour(self, code: int | None) -> str:
         """Return colour for a status code."""
-        if code is None:
-            return "white"
-        if code == 200:
-            return "green"
-        elif code in (301, 302, 307):
-            return "yellow"
-        elif code in (401, 403):
-            return "cyan"
-        elif code >= 500:
-            return "red"
-        return "white"
+        return status_colour(code, self._status_colours)
+
+    def _active_elapsed(self) -> float:
+        """Seconds since the scan started, not counting time spent paused."""
+        now = time.time()
+        paused = self._paused_total
+        if self._paused_since is not None:
+            paused += now - self._paused_since
+        return now - self._start_time - paused
+
+    def action_toggle_pause(self) -> None:
+        """Suspend or continue the running tools (SIGSTOP/SIGCONT)."""
+        scanners = [s for s in (self._scanner, self._vhost_scanner) if s]
+        if self._paused_since is None:
+            if not any([s.pause() for s in scanners]):
+                self.notify("Nothing to pause", severity="warning")
+                return
+            self._paused_since = time.time()
+            self.notify("Scan paused, press p to resume")
+        else:
+            for scanner in scanners:
+                scanner.resume()
+            self._paused_total += time.time() - self._paused_since
+            self._paused_since = None
+            self.notify("Scan resumed")
+        self._refresh_stats()
 
     def action_cancel_scan(self) -> None:
         """Cancel the running scan."""
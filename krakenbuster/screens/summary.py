@@ -13,7 +13,16 @@ from rich.console import Console
 from rich.text import Text
 from io import StringIO
 
-from krakenbuster.output import ScanResult
+from krakenbuster.config import load_config, load_interesting_keywords
+from krakenbuster.output import (
+    ScanResult,
+    cluster_vhosts,
+    detect_redirect_loops,
+    highlight_interesting,
+    mark_size_anomalies,
+    truncate_url,
+)
+from krakenbuster.scanners.helpers import classify_stderr
 
 
 class SummaryScreen(Screen):
@@ -95,6 +104,11 @@ class SummaryScreen(Screen):
             ]
             for line in result.stderr_lines[-20:]:
                 stderr_lines.append(f"  [red]{line}[/red]")
+            summary, hint = classify_stderr(result.stderr_lines)
+            if summary:
+                stderr_lines.append("")
+                stderr_lines.append(f"[bold yellow]Likely cause: {summary}[/bold yellow]")
+                stderr_lines.append(f"  [yellow]{hint}[/yellow]")
             stderr_widget = self.query_one("#summary-stderr", Static)
             stderr_widget.update("\n".join(stderr_lines))
 
@@ -109,10 +123,56 @@ class SummaryScreen(Screen):
         table.add_column("Example URL", style="white", min_width=40)
 
         for status, items in sorted(result.findings_by_status.items()):
-            example = items[0].url if items[0].url else "N/A"
+            example = truncate_url(items[0].url) if items[0].url else "N/A"
             table.add_row(str(status), str(len(items)), example)
 
         console.print(table)
+
+        if result.mode == "vhost":
+            for cluster in cluster_vhosts(result.findings):
+                console.print(f"[yellow]{cluster.describe()}[/yellow]")
+
+        interesting = highlight_interesting(
+            result.findings, load_interesting_keywords(load_config())
+        )
+        if interesting:
+            interesting_table = Table(title="Interesting Findings", border_style="bold yellow")
+            interesting_table.add_column("Status Code", style="cyan", width=12, justify="center")
+            interesting_table.add_column("URL", style="bold yellow", min_width=40)
+            for finding in interesting:
+                interesting_table.add_row(
+                    str(finding.status_code), truncate_url(finding.url) if finding.url else "N/A"
+                )
+            console.print(interesting_table)
+
+        anomalies = mark_size_anomalies(result.findings)
+        if anomalies:
+            anomaly_table = Table(title="Size Anomalies", border_style="cyan")
+            anomaly_table.add_column("Status Code", style="cyan", width=12, justify="center")
+            anomaly_table.add_column("Size", style="bold magenta", width=12, justify="right")
+            anomaly_table.add_column("URL", style="white", min_width=40)
+            for index in sorted(anomalies):
+                finding = result.findings[index]
+                anomaly_table.add_row(
+                    str(finding.status_code),
+                    f"{finding.size:,}",
+                    truncate_url(finding.url) if finding.url else "N/A",
+                )
+            console.print(anomaly_table)
+
+        loops = detect_redirect_loops(result.findings)
+        if loops:
+            loop_table = Table(title="Redirect Loops", border_style="yellow")
+            loop_table.add_column("Status Code", style="cyan", width=12, justify="center")
+            loop_table.add_column("URL", style="white", min_width=40)
+            loop_table.add_column("Redirects To", style="yellow")
+            for index in sorted(loops):
+                finding = result.findings[index]
+                loop_table.add_row(
+                    str(finding.status_code), truncate_url(finding.url), truncate_url(finding.redirect)
+                )
+            console.print(loop_table)
+
         return buf.getvalue()
 
     def on_button_pressed(self, event: Button.Pressed) -> None:
@@ -2,6 +2,7 @@
 
 from __future__ import annotations
 
+import asyncio
 import re
 
 from textual.app import ComposeResult
@@ -10,6 +11,8 @@ from textual.containers import Vertical
 from textual.screen import Screen
 from textual.widgets import Button, Header, Input, Label, Static
 
+from krakenbuster.probe import new_http_client, resolve_scheme
+
 
 def validate_target(target: str, scan_type: str) -> str | None:
     """Validate the target input. Returns an error message or None if valid."""
@@ -25,11 +28,12 @@ def validate_target(target: str, scan_type: str) -> str | None:
         if not re.match(r"^[a-zA-Z0-9]([a-zA-Z0-9\-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9\-]*[a-zA-Z0-9])?)*$", target):
             return "Invalid domain format"
     else:
-        # Directory and vhost modes: must begin with http:// or https://
+        # Directory and vhost modes: must begin with http:// or https://,
+        # though bare hosts are given a scheme by the screen before this check
         if not (target.startswith("http://") or target.startswith("https://")):
             return "Target must begin with http:// or https://"
         # Basic URL validation
-        url_pattern = r"^https?://[a-zA-Z0-9\-\.\:]+(/.*)?$"
+        url_pattern = r"^https?://(\[[0-9a-fA-F:.]+\](:\d+)?|[a-zA-Z0-9\-\.\:]+)(/.*)?$"
         if not re.match(url_pattern, target):
             return "Invalid URL format"
 
@@ -65,7 +69,7 @@ class TargetScreen(Screen):
                 )
             else:
                 yield Static(
-                    "[dim]Enter the target URL with protocol (e.g. https://target.com)[/dim]",
+                    "[dim]Enter the target URL (e.g. https://target.com); a bare host is probed for https, then http[/dim]",
                     id="target-help",
                 )
                 yield Input(
@@ -95,7 +99,27 @@ class TargetScreen(Screen):
         target_input = self.query_one("#target-input", Input)
         target = target_input.value.strip()
         scan_type = getattr(self.app, "scan_type", "directory")
+        error_label = self.query_one("#target-error", Label)
 
+        if scan_type != "dns" and target and "://" not in target:
+            error_label.update("[dim]No scheme given, probing https then http...[/dim]")
+            self.run_worker(self._resolve_scheme(target), exclusive=True)
+            return
+
+        self._accept_target(target, scan_type)
+
+    async def _resolve_scheme(self, target: str) -> None:
+        """Find a working scheme for a bare host, then validate as usual."""
+        client = new_http_client({"timeout": "5"})
+        try:
+            target = await asyncio.to_thread(resolve_scheme, target, client)
+        except OSError as exc:
+            self.query_one("#target-error", Label).update(f"[bold red]{exc}[/bold red]")
+            return
+        self.query_one("#target-input", Input).value = target
+        self._accept_target(target, getattr(self.app, "scan_type", "directory"))
+
+    def _accept_target(self, target: str, scan_type: str) -> None:
         error = validate_target(target, scan_type)
         error_label = self.query_one("#target-error", Label)
 
@@ -2,12 +2,17 @@
 
 from __future__ import annotations
 
+import os
+
+from textual import events
 from textual.app import ComposeResult
 from textual.binding import Binding
 from textual.containers import Center, Vertical
 from textual.screen import Screen
 from textual.widgets import Button, Static
 
+from krakenbuster.scanners.helpers import install_hint
+
 BANNER = """\
 [bold #88c0d0]
   _  __          _               ____            _
@@ -20,16 +25,24 @@ BANNER = """\
 [dim]      Guided scanner orchestration for pentesters[/dim]
 """
 
-TOOL_INSTALL_HINTS = {
-    "feroxbuster": "sudo apt install feroxbuster",
-    "ffuf": "sudo apt install ffuf",
-    "gobuster": "sudo apt install gobuster",
-    "dirb": "sudo apt install dirb",
-    "wfuzz": "sudo apt install wfuzz",
-    "dirsearch": "sudo apt install dirsearch",
-    "amass": "sudo apt install amass",
-    "subfinder": "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest",
-}
+# One-line banner for terminals narrower than the ASCII art, or when NO_COLOR
+# is set and the styled art would only add noise to captured output.
+COMPACT_BANNER = "KrakenBuster v1.0.0 - Web Enumeration Toolkit"
+COMPACT_BANNER_WIDTH = 60
+
+
+def banner(width: int) -> str:
+    """Return the banner variant suited to a terminal of the given width."""
+    if width < COMPACT_BANNER_WIDTH or os.environ.get("NO_COLOR"):
+        return COMPACT_BANNER
+    return BANNER
+
+
+# Tools listed on the welcome screen, in display order
+TOOL_NAMES = [
+    "feroxbuster", "ffuf", "gobuster", "dirb", "wfuzz", "dirsearch",
+    "amass", "subfinder",
+]
 
 
 class WelcomeScreen(Screen):
@@ -42,7 +55,7 @@ class WelcomeScreen(Screen):
 
     def compose(self) -> ComposeResult:
         with Vertical(id="welcome-container"):
-            yield Static(BANNER, id="banner")
+            yield Static(self._banner(self.app.size.width), id="banner")
             yield Static("", id="tool-status")
             with Center():
                 yield Button("Continue", id="continue-btn", variant="primary")
@@ -59,10 +72,7 @@ class WelcomeScreen(Screen):
         lines = ["[bold]Tool Availability[/bold]\n"]
         missing_any = False
 
-        for tool_name in [
-            "feroxbuster", "ffuf", "gobuster", "dirb", "wfuzz", "dirsearch",
-            "amass", "subfinder",
-        ]:
+        for tool_name in TOOL_NAMES:
             is_available = available.get(tool_name, False)
             if is_available:
                 icon = "[green]\u2714[/green]"
@@ -74,13 +84,25 @@ class WelcomeScreen(Screen):
         if missing_any:
             lines.append("")
             lines.append("[dim]Install missing tools:[/dim]")
-            for tool_name, hint in TOOL_INSTALL_HINTS.items():
+            if not os.environ.get("PATH"):
+                lines.append("  [dim]PATH is empty, so no tools can be found[/dim]")
+            for tool_name in TOOL_NAMES:
                 if not available.get(tool_name, False):
-                    lines.append(f"  [dim]{hint}[/dim]")
+                    lines.append(f"  [dim]{install_hint(tool_name)}[/dim]")
 
         status_widget = self.query_one("#tool-status", Static)
         status_widget.update("\n".join(lines))
 
+    def on_resize(self, event: events.Resize) -> None:
+        """Switch banner variants when the terminal is resized."""
+        self.query_one("#banner", Static).update(self._banner(event.size.width))
+
+    def _banner(self, width: int) -> str:
+        """Return the banner to show, or nothing if it is turned off."""
+        if not getattr(self.app, "show_banner", True):
+            return ""
+        return banner(width)
+
     def on_button_pressed(self, event: Button.Pressed) -> None:
         if event.button.id == "continue-btn":
             self._continue()
@@ -19,16 +19,40 @@ from textual.widgets import (
 )
 from textual.widgets.tree import TreeNode
 
+from krakenbuster.config import load_config, load_discover_options, save_config
+from krakenbuster.log import logger
+from krakenbuster.probe import detect_extensions, new_http_client
 from krakenbuster.wordlist import (
     WordlistDir,
     WordlistFile,
     discover_wordlists,
     get_all_files,
     human_readable_size,
+    rank_for_target,
     RECOMMENDED,
     count_lines,
+    target_relevance,
 )
 
+# Most wordlists listed in the Suggested group
+SUGGESTED_MAX = 10
+
+
+def restore_browser_state(
+    last_filter: str, last_used: str, files: list[WordlistFile]
+) -> tuple[str, str]:
+    """Return the saved filter and selection that still apply to files.
+
+    The wordlists may have changed since they were saved: a filter that no
+    longer matches any file name is dropped rather than showing an empty
+    tree, and a selected path that is gone is forgotten.
+    """
+    if last_filter and not any(last_filter.lower() in wf.name.lower() for wf in files):
+        last_filter = ""
+    if last_used and not any(str(wf.path) == last_used for wf in files):
+        last_used = ""
+    return last_filter, last_used
+
 
 class WordlistScreen(Screen):
     """Screen for selecting a wordlist file via a hierarchical browser."""
@@ -44,6 +68,8 @@ class WordlistScreen(Screen):
     _selected_path: str = ""
     _manual_mode: bool = False
     _preview_base_lines: list[str] = []
+    _last_used: str = ""
+    _technologies: list[str] = []  # detected on the target, for suggestions
 
     def compose(self) -> ComposeResult:
         yield Header()
@@ -72,10 +98,15 @@ class WordlistScreen(Screen):
             )
 
     def on_mount(self) -> None:
-        """Start async wordlist discovery."""
+        """Start async wordlist discovery, restoring the last filter and choice."""
         manual_input = self.query_one("#wordlist-manual-input", Input)
         manual_input.display = False
-        self.query_one("#wordlist-search", Input).focus()
+        config = load_config()
+        self._last_used = config.get("wordlists", "last_used", fallback="")
+        search = self.query_one("#wordlist-search", Input)
+        with search.prevent(Input.Changed):
+            search.value = config.get("wordlists", "last_filter", fallback="")
+        search.focus()
         self._load_wordlists()
 
     def _load_wordlists(self) -> None:
@@ -84,9 +115,53 @@ class WordlistScreen(Screen):
 
     async def _discover(self) -> None:
         """Discover wordlists in background."""
-        self._wordlist_dirs = await discover_wordlists()
+        self._wordlist_dirs = await discover_wordlists(load_discover_options(load_config()))
         self._all_files = get_all_files(self._wordlist_dirs)
-        self._build_tree()
+        self._technologies = []
+
+        search = self.query_one("#wordlist-search", Input)
+        filter_text, last_used = restore_browser_state(search.value, self._last_used, self._all_files)
+        if filter_text != search.value:
+            with search.prevent(Input.Changed):
+                search.value = filter_text
+        self._build_tree(filter_text)
+
+        if last_used:
+            tree = self.query_one("#wordlist-tree", Tree)
+            node = self._find_node(tree.root, last_used)
+            if node:
+                node_parent = node.parent
+                while node_parent:
+                    node_parent.expand()
+                    node_parent = node_parent.parent
+                tree.select_node(node)
+
+        target = getattr(self.app, "target", "")
+        if target and getattr(self.app, "scan_type", "directory") in ("directory", "combined"):
+            self.run_worker(self._detect_technology(target))
+
+    async def _detect_technology(self, target: str) -> None:
+        """Detect the target's technology and suggest wordlists named for it."""
+        try:
+            technologies, _ = await asyncio.to_thread(
+                detect_extensions, target, new_http_client({"timeout": "5"})
+            )
+        except OSError as exc:
+            logger.debug("technology detection failed: %s", exc)
+            return
+        if technologies:
+            self._technologies = technologies
+            self._build_tree(self.query_one("#wordlist-search", Input).value)
+
+    def _find_node(self, node: TreeNode, path: str) -> TreeNode | None:
+        """Return the leaf under node whose data is path, if any."""
+        if node.data == path:
+            return node
+        for child in node.children:
+            found = self._find_node(child, path)
+            if found:
+                return found
+        return None
 
     def _build_tree(self, filter_text: str = "") -> None:
         """Build or rebuild the tree widget from discovered wordlists."""
@@ -97,6 +172,19 @@ class WordlistScreen(Screen):
         scan_type = getattr(self.app, "scan_type", "directory")
         recommended = RECOMMENDED.get(scan_type, [])
 
+        if self._technologies:
+            suggested = [
+                wf for wf in rank_for_target(self._all_files, self._technologies)
+                if target_relevance(wf.path, self._technologies)
+                and (not filter_text or filter_text.lower() in wf.name.lower())
+            ][:SUGGESTED_MAX]
+            if suggested:
+                group = tree.root.add(
+                    f"Suggested for {', '.join(self._technologies)}", expand=True
+                )
+                for wf in suggested:
+                    group.add_leaf(f"{wf.name} ({wf.size_human})", data=str(wf.path))
+
         for wdir in self._wordlist_dirs:
             self._add_dir_node(tree.root, wdir, filter_text, recommended)
 
@@ -218,6 +306,7 @@ class WordlistScreen(Screen):
             self._validate_manual_path(manual_input.value)
         elif self._selected_path:
             self.app.wordlist_path = self._selected_path
+            self._remember_selection(self._selected_path)
             self.app.go_to_options()
         else:
             error_label = self.query_one("#wordlist-error", Label)
@@ -236,14 +325,31 @@ class WordlistScreen(Screen):
             error_label.update(f"[bold red]File not found: {path}[/bold red]")
             return
 
+        if path.is_dir():
+            error_label.update(
+                f"[bold red]Wordlist path is a directory, not a file: {path}[/bold red]"
+            )
+            return
+
         if not path.is_file():
             error_label.update(f"[bold red]Not a file: {path}[/bold red]")
             return
 
         error_label.update("")
         self.app.wordlist_path = str(path)
+        self._remember_selection(str(path))
         self.app.go_to_options()
 
+    def _remember_selection(self, path: str) -> None:
+        """Save the chosen wordlist and current filter for the next launch."""
+        config = load_config()
+        config["wordlists"]["last_used"] = path
+        config["wordlists"]["last_filter"] = self.query_one("#wordlist-search", Input).value
+        try:
+            save_config(config)
+        except OSError as exc:
+            logger.warning("cannot save wordlist selection: %s", exc)
+
     def action_confirm(self) -> None:
         self._try_continue()
 
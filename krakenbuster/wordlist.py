@@ -3,8 +3,20 @@
 from __future__ import annotations
 
 import asyncio
+import hashlib
+import json
+import os
+import random
+import shutil
+import tempfile
+from array import array
+from concurrent.futures import ThreadPoolExecutor
 from dataclasses import dataclass, field
 from pathlib import Path
+from typing import TYPE_CHECKING, BinaryIO, Callable
+
+if TYPE_CHECKING:
+    from krakenbuster.probe import HttpClient
 
 WORDLIST_DIRS = [
     Path("/usr/share/wordlists"),
@@ -13,6 +25,26 @@ WORDLIST_DIRS = [
     Path("/usr/share/dirbuster/wordlists"),
 ]
 
+# Upper bound on wordlist roots walked at once
+DISCOVERY_WORKERS = 4
+
+# Cached discovery results, validated against directory mtimes
+DISCOVERY_CACHE_PATH = Path.home() / ".krakenbuster_wordlists.json"
+
+
+def _user_cache_dir() -> Path:
+    """Return $XDG_CACHE_HOME/krakenbuster, or ~/.cache/krakenbuster if it is unset."""
+    base = os.environ.get("XDG_CACHE_HOME", "")
+    return (Path(base) if os.path.isabs(base) else Path.home() / ".cache") / "krakenbuster"
+
+
+# Downloaded --wordlist-url lists, keyed by URL hash. The cache is per user,
+# as a list planted in a shared temp directory would be trusted on reuse.
+WORDLIST_CACHE_DIR = _user_cache_dir() / "wordlists"
+
+# Extensions accepted as wordlists when no DiscoverOptions are given
+DEFAULT_WORDLIST_EXTENSIONS = (".txt", ".lst", ".dic")
+
 RECOMMENDED = {
     "directory": [
         "raft-medium-words.txt",
@@ -27,6 +59,16 @@ RECOMMENDED = {
 }
 
 
+# Path keywords marking a wordlist as suited to each technology reported by
+# probe.detect_extensions(), for --smart-wordlist and the TUI suggestions
+TECH_WORDLIST_KEYWORDS: dict[str, list[str]] = {
+    "PHP": ["php", "wordpress", "drupal", "joomla", "laravel"],
+    "ASP.NET": ["asp", "iis", "sharepoint"],
+    "Java": ["jsp", "tomcat", "spring", "struts", "jboss"],
+    "ColdFusion": ["coldfusion", "cfm"],
+}
+
+
 def human_readable_size(size_bytes: int) -> str:
     """Convert byte count to human-readable string."""
     if size_bytes < 1024:
@@ -39,6 +81,30 @@ def human_readable_size(size_bytes: int) -> str:
         return f"{size_bytes / (1024 * 1024 * 1024):.1f} GB"
 
 
+@dataclass
+class DiscoverOptions:
+    """Which files wordlist discovery treats as wordlists."""
+
+    extensions: tuple[str, ...] = DEFAULT_WORDLIST_EXTENSIONS
+    include_extensionless: bool = False
+
+    def accepts(self, path: Path) -> bool:
+        """Return True if path should be listed as a wordlist."""
+        if path.suffix:
+            return path.suffix.lower() in self.extensions
+        return self.include_extensionless and _looks_like_text(path)
+
+
+def _looks_like_text(path: Path) -> bool:
+    """Guess whether a file is text: non-empty with no NUL in the first KB."""
+    try:
+        with open(path, "rb") as fh:
+            head = fh.read(1024)
+    except OSError:
+        return False
+    return bool(head) and b"\0" not in head
+
+
 @dataclass
 class WordlistFile:
     """A single wordlist file."""
@@ -88,47 +154,162 @@ class WordlistDir:
         return count
 
 
-def _scan_directory(base_path: Path) -> WordlistDir | None:
-    """Scan a directory for wordlist files, building a tree structure."""
+def _scan_directory(
+    base_path: Path,
+    options: DiscoverOptions,
+    mtimes: dict[str, float] | None = None,
+) -> WordlistDir | None:
+    """Scan a directory for wordlist files, building a tree structure.
+
+    When mtimes is given, the modification time of every directory visited
+    is recorded in it so a cached result can be validated later.
+    """
     if not base_path.exists() or not base_path.is_dir():
         return None
 
     root = WordlistDir(path=base_path, name=base_path.name)
 
+    if mtimes is not None:
+        try:
+            mtimes[str(base_path)] = base_path.stat().st_mtime
+        except OSError:
+            pass
+
     try:
         entries = sorted(base_path.iterdir())
     except PermissionError:
         return root
 
     for entry in entries:
-        if entry.is_file() and entry.suffix == ".txt":
+        if entry.is_file() and options.accepts(entry):
             try:
                 wf = WordlistFile(path=entry, size=entry.stat().st_size)
                 root.files.append(wf)
             except (OSError, PermissionError):
                 pass
         elif entry.is_dir():
-            subdir = _scan_directory(entry)
+            subdir = _scan_directory(entry, options, mtimes)
             if subdir and subdir.total_count > 0:
                 root.subdirs.append(subdir)
 
     return root
 
 
-async def discover_wordlists() -> list[WordlistDir]:
-    """Discover all wordlist directories in background thread."""
-    results: list[WordlistDir] = []
+def _scan_roots(
+    roots: list[Path],
+    options: DiscoverOptions,
+    mtimes: dict[str, float] | None = None,
+) -> list[WordlistDir]:
+    """Scan roots in parallel, keeping those that contain wordlists.
+
+    Each root is walked by its own worker with a private mtimes dict, and
+    results are merged in root order so the output does not depend on which
+    walk finishes first.
+    """
+    def scan(base: Path) -> tuple[WordlistDir | None, dict[str, float]]:
+        local: dict[str, float] = {}
+        return _scan_directory(base, options, local), local
+
+    workers = max(1, min(DISCOVERY_WORKERS, len(roots)))
+    with ThreadPoolExecutor(max_workers=workers) as pool:
+        scanned = list(pool.map(scan, roots))
+
+    dirs = []
+    for result, local in scanned:
+        if mtimes is not None:
+            mtimes.update(local)
+        if result and result.total_count > 0:
+            dirs.append(result)
+    return dirs
+
+
+def _dir_to_dict(wdir: WordlistDir) -> dict:
+    """Serialise a WordlistDir tree for the discovery cache."""
+    return {
+        "path": str(wdir.path),
+        "files": [[f.name, f.size] for f in wdir.files],
+        "subdirs": [_dir_to_dict(sub) for sub in wdir.subdirs],
+    }
+
+
+def _dir_from_dict(data: dict) -> WordlistDir:
+    """Rebuild a WordlistDir tree from its cached form."""
+    path = Path(data["path"])
+    return WordlistDir(
+        path=path,
+        files=[WordlistFile(path=path / name, size=size) for name, size in data["files"]],
+        subdirs=[_dir_from_dict(sub) for sub in data["subdirs"]],
+    )
+
+
+def _cache_key(roots: list[Path], options: DiscoverOptions) -> dict:
+    """Describe the inputs a cached discovery result is only valid for."""
+    return {
+        "roots": [str(root) for root in roots],
+        "extensions": list(options.extensions),
+        "include_extensionless": options.include_extensionless,
+    }
+
+
+def _load_discovery_cache(roots: list[Path], options: DiscoverOptions) -> list[WordlistDir] | None:
+    """Return cached discovery results, or None if the cache is missing or stale."""
+    try:
+        data = json.loads(DISCOVERY_CACHE_PATH.read_text())
+        if data["key"] != _cache_key(roots, options):
+            return None
+        for path, mtime in data["mtimes"].items():
+            current = os.stat(path).st_mtime if os.path.exists(path) else None
+            if current != mtime:
+                return None
+        return [_dir_from_dict(d) for d in data["dirs"]]
+    except (OSError, ValueError, KeyError, TypeError):
+        return None
 
-    def _scan_all() -> list[WordlistDir]:
-        dirs = []
-        for base in WORDLIST_DIRS:
-            result = _scan_directory(base)
-            if result and result.total_count > 0:
-                dirs.append(result)
-        return dirs
 
-    results = await asyncio.to_thread(_scan_all)
-    return results
+def _save_discovery_cache(
+    roots: list[Path],
+    options: DiscoverOptions,
+    mtimes: dict,
+    dirs: list[WordlistDir],
+) -> None:
+    """Write discovery results to the cache, ignoring failures."""
+    data = {
+        "key": _cache_key(roots, options),
+        "mtimes": mtimes,
+        "dirs": [_dir_to_dict(d) for d in dirs],
+    }
+    try:
+        DISCOVERY_CACHE_PATH.write_text(json.dumps(data))
+    except OSError:
+        pass
+
+
+def discover_wordlists_cached(
+    roots: list[Path] | None = None,
+    options: DiscoverOptions | None = None,
+) -> list[WordlistDir]:
+    """Discover wordlists under roots, reusing cached results when still valid.
+
+    The cache records the modification time of every directory walked, plus
+    a marker for roots that did not exist, and is discarded as soon as any
+    of them changes. Any cache error falls back to a full walk.
+    """
+    roots = WORDLIST_DIRS if roots is None else roots
+    options = options or DiscoverOptions()
+    cached = _load_discovery_cache(roots, options)
+    if cached is not None:
+        return cached
+
+    # Missing roots are recorded as None so creating one later busts the cache
+    mtimes: dict[str, float | None] = {str(root): None for root in roots}
+    dirs = _scan_roots(roots, options, mtimes)
+    _save_discovery_cache(roots, options, mtimes, dirs)
+    return dirs
+
+
+async def discover_wordlists(options: DiscoverOptions | None = None) -> list[WordlistDir]:
+    """Discover all wordlist directories in background thread."""
+    return await asyncio.to_thread(discover_wordlists_cached, None, options)
 
 
 async def count_lines(wordlist_path: Path) -> int:
@@ -159,3 +340,205 @@ def get_all_files(dirs: list[WordlistDir]) -> list[WordlistFile]:
     for d in dirs:
         _collect(d)
     return files
+
+
+def target_relevance(path: Path, technologies: list[str]) -> int:
+    """Count the technologies with a TECH_WORDLIST_KEYWORDS keyword in path."""
+    text = str(path).lower()
+    return sum(
+        any(keyword in text for keyword in TECH_WORDLIST_KEYWORDS.get(tech, []))
+        for tech in technologies
+    )
+
+
+def rank_for_target(
+    files: list[WordlistFile], technologies: list[str], scan_type: str = "directory"
+) -> list[WordlistFile]:
+    """Order files by target_relevance(), most relevant first.
+
+    Among equally relevant files, those recommended for scan_type come
+    first; otherwise the original order is kept.
+    """
+    return sorted(
+        files,
+        key=lambda wf: (-target_relevance(wf.path, technologies), not wf.is_recommended(scan_type)),
+    )
+
+
+def check_wordlist_path(path: str) -> None:
+    """Check that path names a readable wordlist file.
+
+    Raises ValueError with a clear message when path is a directory, and
+    OSError when it does not exist or cannot be opened.
+    """
+    if os.path.isdir(path):
+        raise ValueError(f"wordlist path is a directory, not a file: {path}")
+    with open(path, "rb"):
+        pass
+
+
+def _temp_wordlist() -> tuple[str, Callable[[], None]]:
+    """Create an empty temporary wordlist file and a callable that removes it."""
+    fd, path = tempfile.mkstemp(prefix="krakenbuster_", suffix=".txt")
+    os.close(fd)
+
+    def cleanup() -> None:
+        try:
+            os.remove(path)
+        except OSError:
+            pass
+
+    return path, cleanup
+
+
+def write_wordlist(words: list[str]) -> tuple[str, Callable[[], None]]:
+    """Write words to a temporary wordlist, one per line.
+
+    Returns the path and a cleanup callable. Raises OSError if the file
+    cannot be written.
+    """
+    path, cleanup = _temp_wordlist()
+    try:
+        with open(path, "w") as out:
+            out.writelines(word + "\n" for word in words)
+    except OSError:
+        cleanup()
+        raise
+    return path, cleanup
+
+
+def read_stdin_wordlist(stream: BinaryIO) -> tuple[str, Callable[[], None]]:
+    """Copy a wordlist from a stream (normally stdin) into a temporary file.
+
+    The external tools need a file path, so the stream is drained in chunks.
+    Returns the path and a cleanup callable. Raises ValueError if the stream
+    holds no entries.
+    """
+    path, cleanup = _temp_wordlist()
+    try:
+        with open(path, "wb") as out:
+            shutil.copyfileobj(stream, out)
+        with open(path, "rb") as fh:
+            empty = not any(line.strip() for line in fh)
+    except OSError:
+        cleanup()
+        raise
+    if empty:
+        cleanup()
+        raise ValueError("wordlist on stdin is empty")
+    return path, cleanup
+
+
+def combine_wordlists(paths: list[str]) -> tuple[str, Callable[[], None]]:
+    """Concatenate several wordlists into one temporary file.
+
+    Entries are streamed and deduplicated keeping first-seen order, so large
+    lists are never fully loaded. Returns the combined path and a cleanup
+    callable; a single path is returned unchanged with a no-op cleanup.
+    """
+    if len(paths) == 1:
+        return paths[0], lambda: None
+
+    combined, cleanup = _temp_wordlist()
+    seen: set[str] = set()
+    try:
+        with open(combined, "w") as out:
+            for path in paths:
+                with open(path, "r", errors="ignore") as fh:
+                    for line in fh:
+                        word = line.rstrip("\r\n")
+                        if word and word not in seen:
+                            seen.add(word)
+                            out.write(word + "\n")
+    except OSError:
+        cleanup()
+        raise
+    return combined, cleanup
+
+
+def shuffle_wordlist(path: str, source: random.Random) -> tuple[str, Callable[[], None]]:
+    """Write the entries of a wordlist in random order to a temporary file.
+
+    Only the byte offset of each entry is held in memory; the entries are
+    read back from the original in shuffled order, so large lists can be
+    shuffled. Blank lines are dropped. The order comes from source, so a
+    seeded source repeats it. Returns the path and a cleanup callable.
+    """
+    offsets = array("q")
+    with open(path, "rb") as fh:
+        offset = 0
+        for line in fh:
+            if line.strip():
+                offsets.append(offset)
+            offset += len(line)
+    source.shuffle(offsets)
+
+    shuffled, cleanup = _temp_wordlist()
+    try:
+        with open(path, "rb") as fh, open(shuffled, "wb") as out:
+            for offset in offsets:
+                fh.seek(offset)
+                out.write(fh.readline().rstrip(b"\r\n") + b"\n")
+    except OSError:
+        cleanup()
+        raise
+    return shuffled, cleanup
+
+
+def head_wordlist(path: str, limit: int) -> tuple[str, Callable[[], None]]:
+    """Copy the first limit entries of a wordlist to a temporary file.
+
+    Blank lines are skipped and do not count towards limit; a shorter list
+    is copied whole. Returns the path and a cleanup callable.
+    """
+    head, cleanup = _temp_wordlist()
+    written = 0
+    try:
+        with open(path, "rb") as fh, open(head, "wb") as out:
+            for line in fh:
+                if written >= limit:
+                    break
+                if line.strip():
+                    out.write(line.rstrip(b"\r\n") + b"\n")
+                    written += 1
+    except OSError:
+        cleanup()
+        raise
+    return head, cleanup
+
+
+def fetch_wordlist(url: str, client: HttpClient) -> str:
+    """Download a remote wordlist and return the path of a cached copy.
+
+    The cache is keyed by a hash of the URL, so repeated runs reuse the file
+    instead of downloading it again. Raises OSError if the request fails and
+    ValueError for a non-200 response or an empty list.
+    """
+    cached = WORDLIST_CACHE_DIR / f"{hashlib.sha256(url.encode()).hexdigest()[:16]}.txt"
+    try:
+        if cached.stat().st_size > 0:
+            return str(cached)
+    except OSError:
+        pass
+
+    resp = client.get(url)
+    if resp.status_code != 200:
+        raise ValueError(f"{url} returned HTTP {resp.status_code}")
+    if not resp.body.strip():
+        raise ValueError(f"{url} returned an empty wordlist")
+
+    # Write to a temporary name first so an interrupted download never
+    # leaves a truncated file behind for the next run to reuse.
+    WORDLIST_CACHE_DIR.mkdir(mode=0o700, parents=True, exist_ok=True)
+    fd, tmp = tempfile.mkstemp(dir=WORDLIST_CACHE_DIR, suffix=".part")
+    try:
+        with os.fdopen(fd, "wb") as fh:
+            fh.write(resp.body)
+        os.replace(tmp, cached)
+    except OSError:
+        try:
+            os.remove(tmp)
+        except OSError:
+            pass
+        raise
+    return str(cached)
@@ -0,0 +1,49 @@
+"""Test doubles for the tools KrakenBuster wraps."""
+
+from __future__ import annotations
+
+import asyncio
+from types import SimpleNamespace
+
+
+class FakeTool:
+    """An Executor that records each command and answers it with canned output.
+
+    Version probes (the tool's version flag alone) get version; any other
+    command gets lines on stdout and stderr_lines on stderr, then exits
+    with returncode.
+    """
+
+    def __init__(self, lines=(), stderr_lines=(), returncode=0, version="fake 1.0"):
+        self.lines = list(lines)
+        self.stderr_lines = list(stderr_lines)
+        self.returncode = returncode
+        self.version = version
+        self.commands: list[list[str]] = []
+
+    async def __call__(self, command, cwd):
+        self.commands.append(list(command))
+        probe = len(command) <= 2 and command[-1] in ("-V", "--version", "version", "-version")
+        out = [self.version] if probe else self.lines
+        err = [] if probe else self.stderr_lines
+        stdout, stderr = asyncio.StreamReader(), asyncio.StreamReader()
+        for line in out:
+            stdout.feed_data(line.encode() + b"\n")
+        for line in err:
+            stderr.feed_data(line.encode() + b"\n")
+        stdout.feed_eof()
+        stderr.feed_eof()
+        returncode = 0 if probe else self.returncode
+
+        async def wait():
+            return returncode
+
+        return SimpleNamespace(
+            stdout=stdout, stderr=stderr, returncode=returncode, wait=wait,
+            terminate=lambda: None, kill=lambda: None, send_signal=lambda sig: None,
+        )
+
+    @property
+    def scans(self) -> list[list[str]]:
+        """The commands other than version probes."""
+        return [c for c in self.commands if c[1:] and c[1] not in ("-V", "--version", "version")]
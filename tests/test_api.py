@@ -0,0 +1,63 @@
+import asyncio
+import json
+
+import pytest
+
+from krakenbuster.api import scan, scan_async
+from tests.fakes import FakeTool
+
+FFUF_LINE = "admin [Status: 200, Size: 42, Words: 3, Lines: 1, Duration: 5ms]"
+
+
+def test_scan_replays_fake_executor(tmp_path, wordlist):
+    tool = FakeTool([FFUF_LINE], version="ffuf version: 2.1.0")
+    result = scan("https://target.com", wordlist, dir_tool="ffuf", executor=tool,
+                  output_dir=str(tmp_path))
+
+    assert not result.dir_error
+    assert [(f.status_code, f.size, f.inputs) for f in result.dir_result.findings] == [
+        (200, 42, {"FUZZ": "admin"}),
+    ]
+    assert tool.commands[0] == ["ffuf", "-V"]
+    assert tool.scans and tool.scans[0][0] == "ffuf"
+
+
+def test_tool_version_comes_from_executor(tmp_path, wordlist):
+    tool = FakeTool([FFUF_LINE], version="ffuf version: 2.1.0")
+    result = scan("https://target.com", wordlist, dir_tool="ffuf", executor=tool,
+                  output_dir=str(tmp_path))
+
+    config = json.loads(open(result.dir_result._config_path).read())
+    assert config["tool_version"] == "ffuf version: 2.1.0"
+
+
+def test_concurrent_scans_keep_their_own_executor(tmp_path, wordlist):
+    first = FakeTool(["admin [Status: 200, Size: 1, Words: 1, Lines: 1]"])
+    second = FakeTool(["login [Status: 403, Size: 2, Words: 1, Lines: 1]"])
+
+    async def both():
+        return await asyncio.gather(
+            scan_async("https://one.test", wordlist, dir_tool="ffuf", executor=first,
+                       output_dir=str(tmp_path / "one")),
+            scan_async("https://two.test", wordlist, dir_tool="ffuf", executor=second,
+                       output_dir=str(tmp_path / "two")),
+        )
+
+    one, two = asyncio.run(both())
+    assert [f.inputs for f in one.dir_result.findings] == [{"FUZZ": "admin"}]
+    assert [f.inputs for f in two.dir_result.findings] == [{"FUZZ": "login"}]
+    assert all("https://one.test/FUZZ" in " ".join(c) for c in first.scans)
+    assert all("https://two.test/FUZZ" in " ".join(c) for c in second.scans)
+
+
+def test_failing_tool_marks_result_failed(tmp_path, wordlist):
+    tool = FakeTool(stderr_lines=["error: bad flag"], returncode=2)
+    result = scan("https://target.com", wordlist, dir_tool="ffuf", executor=tool,
+                  output_dir=str(tmp_path))
+    assert result.dir_result.failed
+    assert result.dir_result.stderr_lines == ["error: bad flag"]
+
+
+def test_scan_needs_a_tool(wordlist):
+    with pytest.raises(ValueError, match="dir_tool"):
+        scan("https://target.com", wordlist)
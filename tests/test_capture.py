@@ -0,0 +1,152 @@
+import asyncio
+import gzip
+import time
+from http.server import BaseHTTPRequestHandler
+
+import pytest
+from rich.console import Console
+
+from krakenbuster.output import Finding, capture_response, hash_and_group, should_capture, slowest_findings
+from krakenbuster.probe import new_http_client
+from krakenbuster.runner import _capture_findings
+
+
+# Delay before the /slow page answers
+SLOW_SECONDS = 0.2
+
+
+class _Pages(BaseHTTPRequestHandler):
+    """Serves /error as a 500, /unique with its own body and any other path as one page.
+
+    /slow answers SLOW_SECONDS late.
+    """
+
+    def do_GET(self):
+        if self.path == "/slow":
+            time.sleep(SLOW_SECONDS)
+        if self.path == "/error":
+            self.send_response(500)
+            body = b"boom"
+        elif self.path == "/unique":
+            self.send_response(200)
+            body = b"<html>only here</html>"
+        else:
+            self.send_response(200)
+            body = b"<html>admin panel</html>"
+        self.send_header("X-Served-By", "test")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+class _Gzipped(BaseHTTPRequestHandler):
+    """Serves a small page gzipped to clients that accept gzip."""
+
+    def do_GET(self):
+        body = b"<html>" + b"compressed " * 50 + b"</html>"
+        self.send_response(200)
+        if "gzip" in self.headers.get("Accept-Encoding", ""):
+            body = gzip.compress(body)
+            self.send_header("Content-Encoding", "gzip")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+@pytest.mark.parametrize("finding, captured", [
+    (Finding(status_code=200, url="https://t.test/a"), True),
+    (Finding(status_code=503, url="https://t.test/a"), True),
+    (Finding(status_code=403, url="https://t.test/a"), False),
+    (Finding(status_code=301, url="https://t.test/a"), False),
+    (Finding(status_code=200, inputs={"FUZZ": "dev"}), False),  # no URL to fetch
+])
+def test_should_capture(finding, captured):
+    assert should_capture(finding) is captured
+
+
+def test_capture_stores_status_headers_and_body(http_server, tmp_path):
+    page_server = http_server(_Pages)
+    path, encoding, _ = capture_response(f"{page_server}/page", new_http_client({}), 1024, tmp_path / "captures")
+
+    text = path.read_text()
+    assert path.parent == tmp_path / "captures"
+    assert text.startswith(f"GET {page_server}/page\nHTTP 200\n")
+    assert "X-Served-By: test" in text
+    assert text.endswith("\n\n<html>admin panel</html>")
+    assert encoding == ""
+
+
+def test_capture_truncates_the_body_and_keeps_error_responses(http_server, tmp_path):
+    page_server = http_server(_Pages)
+    path, _, _ = capture_response(f"{page_server}/error", new_http_client({}), 2, tmp_path)
+    assert "HTTP 500" in path.read_text()
+    assert path.read_text().endswith("\n\nbo")
+
+
+def test_hash_and_group_groups_identical_bodies(http_server):
+    page_server = http_server(_Pages)
+    findings = [
+        Finding(status_code=200, url=f"{page_server}/page"),
+        Finding(status_code=200, url=f"{page_server}/copy"),
+        Finding(status_code=200, url=f"{page_server}/unique"),
+        Finding(status_code=403, url=f"{page_server}/other"),
+    ]
+    groups = hash_and_group(findings, new_http_client({}), workers=2)
+
+    assert list(groups.values()) == [[f"{page_server}/page", f"{page_server}/copy"]]
+    [digest] = groups
+    assert findings[0].body_hash == findings[1].body_hash == digest
+    assert findings[2].body_hash not in ("", digest)
+    assert findings[3].body_hash == ""
+
+
+def test_hash_and_group_skips_unreachable_urls():
+    finding = Finding(status_code=200, url="http://127.0.0.1:9/gone")
+
+    assert hash_and_group([finding], new_http_client({"timeout": "1"})) == {}
+    assert finding.body_hash == ""
+
+
+def test_capture_records_gzip_and_stores_the_body_decompressed(http_server, tmp_path):
+    gzip_server = http_server(_Gzipped)
+    path, encoding, _ = capture_response(f"{gzip_server}/page", new_http_client({}), 1024, tmp_path)
+
+    assert encoding == "gzip"
+    assert "Content-Encoding: gzip" in path.read_text()
+    assert path.read_text().endswith("\n\n<html>" + "compressed " * 50 + "</html>")
+
+
+def test_capture_pass_populates_the_finding_encoding(http_server, tmp_path):
+    gzip_server = http_server(_Gzipped)
+    finding = Finding(status_code=200, url=f"{gzip_server}/page", size=60)
+
+    asyncio.run(_capture_findings(Console(quiet=True), [finding], {}, tmp_path))
+
+    assert finding.encoding == "gzip"
+    assert finding.capture and finding.latency_ms >= 0
+
+
+def test_capture_records_the_response_latency(http_server, tmp_path):
+    page_server = http_server(_Pages)
+    client = new_http_client({})
+    _, _, slow = capture_response(f"{page_server}/slow", client, 1024, tmp_path)
+    _, _, fast = capture_response(f"{page_server}/page", client, 1024, tmp_path)
+
+    assert slow >= SLOW_SECONDS * 1000
+    assert fast < slow
+
+
+def test_slowest_findings_lists_the_slowest_timed_findings_first():
+    findings = [
+        Finding(status_code=200, url=f"https://t.test/{ms}", latency_ms=ms) for ms in (30, 0, 900, 5, 120, 60, 400)
+    ]
+
+    assert [f.latency_ms for f in slowest_findings(findings)] == [900, 400, 120, 60, 30]
+    assert [f.latency_ms for f in slowest_findings(findings, 2)] == [900, 400]
+    assert slowest_findings([Finding(status_code=200, url="https://t.test/a")]) == []
@@ -0,0 +1,439 @@
+import asyncio
+import configparser
+import io
+import json
+import random
+
+import click
+import pytest
+from rich.console import Console
+
+from krakenbuster import main
+from krakenbuster.output import Finding, ScanResult
+from krakenbuster.runner import HostResult, ScanSettings, TargetSpec, run_cli_scan
+from krakenbuster.scanners.helpers import USER_AGENTS
+from tests.fakes import FakeTool
+
+
+def _params(command) -> set[str]:
+    return {param.name for param in command.params}
+
+
+@pytest.mark.parametrize("command", [main.dir, main.combined])
+def test_extension_flags_on_path_modes(command):
+    assert {"extensions", "extensions_file"} <= _params(command)
+
+
+@pytest.mark.parametrize("command", [main.dir, main.vhost, main.combined])
+def test_request_and_display_flags_on_every_scan_mode(command):
+    assert {"auto_scheme", "headers", "headers_file", "random_agent", "http2"} <= _params(command)
+    assert {"summary_only", "interactive_filter", "display_rows"} <= _params(command)
+
+
+@pytest.mark.parametrize("command", [main.vhost, main.dns])
+def test_extension_flags_not_on_host_modes(command):
+    assert not {"extensions", "extensions_file"} & _params(command)
+
+
+def _config(no_banner: str) -> configparser.ConfigParser:
+    config = configparser.ConfigParser()
+    config.read_dict({"display": {"no_banner": no_banner}})
+    return config
+
+
+def test_no_banner_from_flag_quiet_or_config(monkeypatch):
+    monkeypatch.setattr(main, "load_config", lambda: _config("false"))
+    assert main._no_banner(False, False) is False
+    assert main._no_banner(True, False) is True
+    assert main._no_banner(False, True) is True
+
+    monkeypatch.setattr(main, "load_config", lambda: _config("true"))
+    assert main._no_banner(False, False) is True
+
+
+def test_invalid_config_value_keeps_banner(monkeypatch):
+    monkeypatch.setattr(main, "load_config", lambda: _config("maybe"))
+    assert main._no_banner(False, False) is False
+
+
+def test_quiet_makes_scans_summary_only(monkeypatch):
+    monkeypatch.setattr(main, "settings", main.CliSettings(no_banner=True, quiet=True))
+    scan = main._scan_settings(summary_only=False)
+    assert scan.summary_only and not scan.banner
+
+
+def test_suppressed_banner_prints_nothing(tmp_path, wordlist):
+    out = io.StringIO()
+    tool = FakeTool(["admin [Status: 200, Size: 1, Words: 1, Lines: 1]"])
+    settings = ScanSettings(console=Console(file=out), executor=tool, output_dir=str(tmp_path),
+                            banner=False)
+    asyncio.run(run_cli_scan("directory", "ffuf", "https://t.test", wordlist, {}, settings))
+
+    assert "KrakenBuster" not in out.getvalue()
+    assert "Target:" in out.getvalue()
+
+
+@pytest.mark.parametrize("command", [main.dir, main.vhost, main.combined])
+def test_http2_flag(command):
+    assert "http2" in _params(command)
+
+
+def test_check_http2_warns_once_per_problem(monkeypatch):
+    warnings = []
+    monkeypatch.setattr(main.logger, "warning", lambda msg, *args: warnings.append(msg % args))
+
+    main._check_http2("ffuf", ["https://a.test", "https://b.test"])
+    assert warnings == []
+    main._check_http2("ffuf", ["https://a.test", "http://b.test"])
+    main._check_http2("gobuster", ["https://a.test"])
+    assert len(warnings) == 2
+    assert "h2c" in warnings[0] and "gobuster" in warnings[1]
+
+
+@pytest.mark.parametrize("flag, value", [
+    ("headers", ("X-Test: 1",)), ("headers_file", "h.txt"), ("random_agent", True),
+    ("hmac_key", "secret"), ("extensions", "php"), ("extensions_file", "ext.txt"),
+])
+def test_request_file_refuses_dropped_flags(monkeypatch, flag, value):
+    context = type("Context", (), {"params": {"request_file": "req.txt", flag: value}})()
+    monkeypatch.setattr(main.click, "get_current_context", lambda: context)
+    with pytest.raises(SystemExit) as exc:
+        main._check_flags()
+    assert exc.value.code == main.EXIT_USAGE
+
+
+def test_request_host_outside_domain_warns(tmp_path, monkeypatch):
+    warnings = []
+    monkeypatch.setattr(main.logger, "warning", lambda msg, *args: warnings.append(msg % args))
+    path = tmp_path / "req.txt"
+    path.write_text("GET / HTTP/1.1\nHost: FUZZ.target.com:8443\n\n")
+
+    main._check_request_host(str(path), "target.com")
+    assert warnings == []
+    main._check_request_host(str(path), "other.com")
+    assert len(warnings) == 1 and "other.com" in warnings[0]
+
+
+def test_load_target_specs_plain(tmp_path):
+    path = tmp_path / "targets.txt"
+    path.write_text("# hosts\nhttps://a.test\n\nhttps://b.test/app\n")
+    assert main.load_target_specs(str(path)) == [TargetSpec("https://a.test"), TargetSpec("https://b.test/app")]
+
+
+@pytest.mark.parametrize("name", ["targets.jsonl", "targets.txt"])
+def test_load_target_specs_jsonl(tmp_path, name):
+    path = tmp_path / name
+    path.write_text(
+        '{"url": "https://a.test", "domain": "a.test", "wordlist": "small.txt"}\n'
+        "# no overrides\n"
+        '{"url": "https://b.test", "domain": null}\n'
+    )
+    assert main.load_target_specs(str(path)) == [
+        TargetSpec("https://a.test", domain="a.test", wordlist="small.txt"),
+        TargetSpec("https://b.test"),
+    ]
+
+
+@pytest.mark.parametrize("line, message", [
+    ("{not json", "line 1: invalid JSON"), ('{"domain": "a.test"}', 'line 1: expected an object with a "url"'),
+])
+def test_load_target_specs_rejects_bad_lines(tmp_path, line, message):
+    path = tmp_path / "targets.jsonl"
+    path.write_text(line + "\n")
+    with pytest.raises(ValueError, match=message):
+        main.load_target_specs(str(path))
+
+
+def _exit_code(*args, **kwargs):
+    with pytest.raises(SystemExit) as exc:
+        main._exit_for_results(*args, **kwargs)
+    return exc.value.code
+
+
+def _scan(*statuses, failed=False):
+    return ScanResult(findings=[Finding(status_code=s) for s in statuses], failed=failed)
+
+
+@pytest.mark.parametrize("scans, kwargs, code", [
+    ([_scan(200)], {}, main.EXIT_OK),
+    ([_scan()], {}, main.EXIT_OK),
+    ([_scan()], {"fail_on_empty": True}, main.EXIT_NO_FINDINGS),
+    ([_scan(200), _scan()], {"fail_on_empty": True}, main.EXIT_OK),
+    ([_scan(200, failed=True)], {}, main.EXIT_SCAN_ERROR),
+    ([_scan(200)], {"errored": True}, main.EXIT_SCAN_ERROR),
+    # A failed tool outranks the findings gate and an empty result
+    ([_scan(200), _scan(failed=True)], {"fail_on_empty": True, "gate": main.FindingsGate()}, main.EXIT_SCAN_ERROR),
+])
+def test_exit_codes(scans, kwargs, code):
+    assert _exit_code(scans, **{"fail_on_empty": False, **kwargs}) == code
+
+
+def test_missing_tool_exit_code(monkeypatch):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+    with pytest.raises(SystemExit) as exc:
+        main._exit_tool_missing("ffuf")
+    assert exc.value.code == main.EXIT_TOOL_MISSING
+
+
+@pytest.mark.parametrize("gate, code", [
+    (main.FindingsGate(), main.EXIT_FINDINGS),
+    (main.FindingsGate(status_codes=[200]), main.EXIT_FINDINGS),
+    (main.FindingsGate(status_codes=[500]), main.EXIT_OK),
+    (main.FindingsGate(threshold=3), main.EXIT_OK),
+    (main.FindingsGate(threshold=2, status_codes=[200, 403]), main.EXIT_FINDINGS),
+])
+def test_fail_on_findings_gate(monkeypatch, gate, code):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+    assert _exit_code([_scan(200), _scan(403)], False, gate) == code
+
+
+def test_fail_on_findings_ignores_empty_scans(monkeypatch):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+    assert _exit_code([_scan()], False, main.FindingsGate()) == main.EXIT_OK
+
+
+def test_output_stdout_prints_only_the_json_document(tmp_path, wordlist, monkeypatch, capsys):
+    monkeypatch.setattr(main, "console", Console())
+    monkeypatch.setattr(main, "settings", main.CliSettings())
+    common = {"output_stdout": "json"}
+    main._claim_stdout(common)
+    tool = FakeTool(["admin [Status: 200, Size: 10, Words: 1, Lines: 1, Duration: 1ms]"])
+    scan = asyncio.run(run_cli_scan(
+        "directory", "ffuf", "https://t.test", wordlist, {},
+        main._scan_settings(executor=tool, output_dir=str(tmp_path)),
+    ))
+    main._write_stdout(common, [scan])
+
+    captured = capsys.readouterr()
+    document = json.loads(captured.out)
+    assert [s["findings"][0]["inputs"] for s in document["scans"]] == [{"FUZZ": "admin"}]
+    assert "Target:" in captured.err
+
+
+@pytest.mark.parametrize("url, tool", [
+    ("127.0.0.1:8080", "ffuf"), ("ftp://127.0.0.1", "ffuf"), ("http://127.0.0.1:8080", "feroxbuster"),
+])
+def test_replay_proxy_rejected(monkeypatch, url, tool):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+    with pytest.raises(SystemExit) as exc:
+        main._replay_proxy(url, tool)
+    assert exc.value.code == main.EXIT_USAGE
+
+
+def test_replay_proxy_accepted():
+    assert main._replay_proxy("socks5://127.0.0.1:1080", "ffuf") == "socks5://127.0.0.1:1080"
+    assert main._replay_proxy("", "feroxbuster") == ""
+
+
+def _flag_problems(monkeypatch, params):
+    """Run _check_flags() on params, returning the panel it printed or "" if it passed."""
+    out = io.StringIO()
+    # Wide enough that the panel does not wrap a problem across lines
+    monkeypatch.setattr(main, "console", Console(file=out, width=400))
+    context = type("Context", (), {"params": params})()
+    monkeypatch.setattr(main.click, "get_current_context", lambda: context)
+    try:
+        main._check_flags()
+    except SystemExit as exc:
+        assert exc.code == main.EXIT_USAGE
+        return out.getvalue()
+    return ""
+
+
+@pytest.mark.parametrize("flag, other, why", main.FLAG_CONFLICTS)
+def test_each_conflicting_pair_is_refused(monkeypatch, flag, other, why):
+    problems = _flag_problems(monkeypatch, {flag: "x", other: "y"})
+    assert f"{main._flag_name(flag)} cannot be used with {main._flag_name(other)}: {why}" in problems
+    assert _flag_problems(monkeypatch, {flag: "x", other: ""}) == ""
+
+
+@pytest.mark.parametrize("flag, needed, why", main.FLAG_REQUIRES)
+def test_each_required_flag_is_checked(monkeypatch, flag, needed, why):
+    assert f"{main._flag_name(flag)} needs {main._flag_name(needed)}: {why}" in _flag_problems(
+        monkeypatch, {flag: "x"}
+    )
+    assert _flag_problems(monkeypatch, {flag: "x", needed: "y"}) == ""
+
+
+@pytest.mark.parametrize("match, filtered", main.STATUS_OVERLAPS)
+def test_overlapping_status_lists_are_refused(monkeypatch, match, filtered):
+    problems = _flag_problems(monkeypatch, {match: "200,301,404", filtered: "404,500"})
+    assert "both list 404" in problems
+    assert _flag_problems(monkeypatch, {match: "200", filtered: "404"}) == ""
+
+
+def test_every_problem_is_reported_together(monkeypatch):
+    problems = _flag_problems(monkeypatch, {"url": "u", "target_list": "t", "scan_secrets": True})
+    assert "give one target source" in problems and "needs --capture" in problems
+
+
+def _scanned(mode, findings=0):
+    scan = ScanResult(mode=mode, findings=[Finding(status_code=200) for _ in range(findings)])
+    scan._json_path = f"/out/{mode}.json"
+    return scan
+
+
+def _combined_summary(monkeypatch, results) -> str:
+    out = io.StringIO()
+    monkeypatch.setattr(main, "console", Console(file=out, width=200))
+    main._print_combined_summary(results)
+    return out.getvalue()
+
+
+@pytest.mark.parametrize("dir_ran, vhost_ran", [(True, False), (False, True), (True, True)])
+def test_combined_summary_shows_only_the_modes_that_ran(monkeypatch, dir_ran, vhost_ran):
+    result = HostResult(
+        "a.test",
+        dir_result=_scanned("directory", 2) if dir_ran else None,
+        vhost_result=_scanned("vhost", 1) if vhost_ran else None,
+    )
+    summary = _combined_summary(monkeypatch, [result])
+
+    assert ("Dir findings" in summary) is dir_ran
+    assert ("Vhost findings" in summary) is vhost_ran
+
+
+@pytest.mark.parametrize("command", [main.dir, main.combined])
+def test_depth_rejects_negative_values(command):
+    depth = {param.name: param for param in command.params}["depth"]
+    assert depth.type.convert(0, depth, None) == 0
+    with pytest.raises(click.BadParameter):
+        depth.type.convert(-1, depth, None)
+
+
+@pytest.mark.parametrize("value", [0, -2])
+def test_concurrency_rejects_values_below_one(value):
+    concurrency = {param.name: param for param in main.combined.params}["concurrency"]
+    assert concurrency.type.convert(1, concurrency, None) == 1
+    with pytest.raises(click.BadParameter):
+        concurrency.type.convert(value, concurrency, None)
+
+
+def test_unlimited_depth_gets_an_output_line_cap(monkeypatch):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+    assert main._line_cap(0, 0) == main.UNLIMITED_DEPTH_LINE_CAP
+    assert main._line_cap(0, 500) == 500
+    assert main._line_cap(1, 0) == 0
+
+
+def test_invalid_exclude_regex_is_rejected_before_the_scan(monkeypatch):
+    out = io.StringIO()
+    monkeypatch.setattr(main, "console", Console(file=out, width=200))
+    with pytest.raises(SystemExit) as exc:
+        main._compile_patterns(("/static/", "(unclosed"), "--exclude-url-regex")
+
+    assert exc.value.code == main.EXIT_USAGE
+    assert "--exclude-url-regex: invalid regex '(unclosed'" in out.getvalue()
+    assert [p.pattern for p in main._compile_patterns(("/static/",), "--exclude-url-regex")] == ["/static/"]
+
+
+def _batch() -> list[HostResult]:
+    return [
+        HostResult("quiet.test", dir_result=_scanned("directory", 1), vhost_result=_scanned("vhost", 0)),
+        HostResult("down.test", dir_error="connection refused", vhost_error="connection refused"),
+        HostResult("busy.test", dir_result=_scanned("directory", 3), vhost_result=_scanned("vhost", 2)),
+    ]
+
+
+def test_batch_summary_table_puts_busiest_host_first(monkeypatch):
+    summary = _combined_summary(monkeypatch, _batch())
+
+    assert summary.index("busy.test") < summary.index("quiet.test") < summary.index("down.test")
+    assert "connection refused" in summary
+
+
+def test_batch_summary_json_covers_every_host(tmp_path):
+    path = main.write_batch_summary(_batch(), str(tmp_path))
+
+    assert path.parent == tmp_path and path.name.startswith("batch_summary_")
+    hosts = json.loads(path.read_text())["hosts"]
+    assert [h["host"] for h in hosts] == ["busy.test", "quiet.test", "down.test"]
+    assert hosts[0]["dir"] == {"findings": 3, "error": "", "output": "/out/directory.json"}
+    assert hosts[2]["dir"] == {"findings": None, "error": "connection refused", "output": ""}
+    assert hosts[2]["vhost"]["error"] == "connection refused"
+
+
+def test_combined_summary_headers_count_each_scan_type(monkeypatch):
+    summary = _combined_summary(monkeypatch, _batch())
+
+    assert "Dir findings (4)" in summary
+    assert "Vhost findings (2)" in summary
+
+
+def test_random_agent_header_is_seeded_and_yields_to_an_explicit_one(monkeypatch):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+    monkeypatch.setattr(main, "rng", random.Random(5))
+    first = main._resolve_headers(("X-Test: 1",), "", random_agent=True)
+    monkeypatch.setattr(main, "rng", random.Random(5))
+
+    assert main._resolve_headers(("X-Test: 1",), "", random_agent=True) == first
+    name, _, agent = first.splitlines()[1].partition(": ")
+    assert name == "User-Agent" and agent in USER_AGENTS
+    assert main._resolve_headers(("user-agent: mine",), "", random_agent=True) == "user-agent: mine"
+
+
+def test_status_range_rejects_an_inverted_range(monkeypatch):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+
+    assert main._status_range(200, 399) == {"min_status": "200", "max_status": "399"}
+    assert main._status_range(300, 0) == {"min_status": "300", "max_status": "0"}
+    with pytest.raises(SystemExit) as exc:
+        main._status_range(400, 399)
+    assert exc.value.code == main.EXIT_USAGE
+
+
+@pytest.mark.parametrize("only, given, tools", [
+    (None, ("feroxbuster", "ffuf"), ("feroxbuster", "ffuf")),
+    ("dir", ("feroxbuster", "ffuf"), ("feroxbuster", None)),
+    ("vhost", ("feroxbuster", "ffuf"), (None, "ffuf")),
+    ("vhost", (None, "ffuf"), (None, "ffuf")),
+])
+def test_only_drops_the_skipped_scan(only, given, tools):
+    assert main._only_tools(only, *given) == tools
+
+
+@pytest.mark.parametrize("only, given", [(None, ("feroxbuster", None)), ("vhost", ("feroxbuster", None))])
+def test_only_requires_the_tool_of_each_scan_that_runs(monkeypatch, only, given):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+    with pytest.raises(SystemExit) as exc:
+        main._only_tools(only, *given)
+    assert exc.value.code == main.EXIT_USAGE
+
+
+def test_hmac_key_appends_the_signature_header(monkeypatch):
+    monkeypatch.setattr(main, "console", Console(quiet=True))
+    options = {"headers": "Cookie: a=1"}
+    main._add_signed_header(options, "k", "X-Sig", "https://t.test")
+    assert options["headers"].split("\n") == ["Cookie: a=1", main.signed_header("k", "https://t.test", "X-Sig")]
+
+    unsigned = {"headers": ""}
+    main._add_signed_header(unsigned, "", "X-Sig", "https://t.test")
+    assert unsigned == {"headers": ""}
+
+
+def _filter_session(monkeypatch, findings: list[Finding], queries: list[str], rows: int) -> str:
+    out = io.StringIO()
+    console = Console(file=out, force_terminal=True, width=200)
+    answers = iter(queries)
+    monkeypatch.setattr(console, "input", lambda prompt="": next(answers))
+    monkeypatch.setattr(main, "console", console)
+    monkeypatch.setattr(main.sys, "stdin", io.StringIO())
+    monkeypatch.setattr(main.sys.stdin, "isatty", lambda: True)
+    main._interactive_filter([ScanResult(tool="ffuf", mode="directory", findings=findings)], rows)
+    return out.getvalue()
+
+
+def test_display_rows_footer_counts_the_shown_and_matching_findings(monkeypatch):
+    findings = [Finding(status_code=200 if n % 2 else 403, url=f"https://t.test/{n}") for n in range(30)]
+
+    text = _filter_session(monkeypatch, findings, ["200", "403", "t.test", ""], rows=10)
+
+    assert text.count("(showing first 10 of 15;") == 2
+    assert "(showing first 10 of 30;" in text
+
+
+def test_display_rows_footer_is_absent_when_everything_fits(monkeypatch):
+    findings = [Finding(status_code=200, url=f"https://t.test/{n}") for n in range(5)]
+
+    assert "showing first" not in _filter_session(monkeypatch, findings, ["200", "q"], rows=10)
+    assert "showing first" not in _filter_session(monkeypatch, findings * 4, ["200", "q"], rows=0)
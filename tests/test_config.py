@@ -0,0 +1,61 @@
+import configparser
+import logging
+
+from krakenbuster.config import load_discover_options, load_extra_args, load_status_colours
+from krakenbuster.log import logger
+from krakenbuster.output import status_colour
+
+
+class _Records(logging.Handler):
+    def __init__(self):
+        super().__init__()
+        self.messages: list[str] = []
+
+    def emit(self, record):
+        self.messages.append(record.getMessage())
+
+
+def _config(**display) -> configparser.ConfigParser:
+    config = configparser.ConfigParser()
+    config.read_dict({"display": display, "wordlists": {"include_extensionless": "perhaps"}})
+    return config
+
+
+def test_invalid_values_are_logged_not_printed(capsys, monkeypatch):
+    records = _Records()
+    monkeypatch.setattr(logger, "handlers", [records])
+    monkeypatch.setattr(logger, "level", logging.WARNING)
+
+    colours = load_status_colours(_config(color_2xx="#0f0", color_4xx="red"))
+    options = load_discover_options(_config())
+
+    assert colours == {"2xx": "#0f0"}
+    assert not options.include_extensionless
+    assert records.messages == [
+        "ignoring invalid colour for color_4xx: 'red'",
+        "ignoring invalid value for include_extensionless: 'perhaps'",
+    ]
+    assert capsys.readouterr().out == ""
+
+
+def test_load_extra_args(monkeypatch):
+    records = _Records()
+    monkeypatch.setattr(logger, "handlers", [records])
+    config = configparser.ConfigParser()
+    config.read_dict({"tools": {"ffuf_extra_args": "-mc all", "feroxbuster_extra_args": "--unclosed 'quote"}})
+
+    assert load_extra_args(config, "ffuf") == "-mc all"
+    assert load_extra_args(config, "feroxbuster") == ""
+    assert load_extra_args(config, "gobuster") == ""
+    assert records.messages and "feroxbuster_extra_args" in records.messages[0]
+
+
+def test_configured_hex_colour_is_used_for_its_status_class():
+    colours = load_status_colours(_config(color_2xx="#00ff00", color_5xx="#abc"))
+
+    assert status_colour(200, colours) == "#00ff00"
+    assert status_colour(204, colours) == "#00ff00"
+    assert status_colour(503, colours) == "#abc"
+    # Classes without an override keep the default palette
+    assert status_colour(301, colours) == "yellow"
+    assert status_colour(200) == "green"
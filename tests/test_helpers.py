@@ -0,0 +1,228 @@
+import asyncio
+import random
+
+import pytest
+
+from krakenbuster.scanners.helpers import (
+    MAX_CIDR_HOSTS,
+    USER_AGENTS,
+    bracket_ipv6,
+    check_header,
+    check_rate_threads,
+    check_vhost_config,
+    classify_stderr,
+    default_scheme,
+    expand_cidr,
+    install_hint,
+    load_extensions,
+    load_headers,
+    normalise_extensions,
+    random_user_agent,
+    request_file_host,
+    signed_header,
+    tool_version,
+)
+from tests.fakes import FakeTool
+
+
+def test_tool_version_probes_through_executor():
+    tool = FakeTool(version="\nffuf version: 2.1.0\nextra")
+    assert asyncio.run(tool_version("ffuf", tool)) == "ffuf version: 2.1.0"
+    assert tool.commands == [["ffuf", "-V"]]
+
+
+def test_tool_version_unknown_tool_is_not_started():
+    tool = FakeTool()
+    assert asyncio.run(tool_version("dirb", tool)) == ""
+    assert tool.commands == []
+
+
+def test_tool_version_missing_tool():
+    async def missing(command, cwd):
+        raise FileNotFoundError(command[0])
+
+    assert asyncio.run(tool_version("ffuf", missing)) == ""
+
+
+def test_normalise_extensions_dedupes_and_strips_dots():
+    assert normalise_extensions(".php, html,.php\n.txt,,") == "php,html,txt"
+
+
+def test_load_extensions_merges_with_flag_value(tmp_path):
+    path = tmp_path / "ext.txt"
+    path.write_text(".bak\nphp\n\n.old,.bak\n")
+    assert normalise_extensions("php,asp", load_extensions(str(path))) == "php,asp,bak,old"
+
+
+def test_request_file_host(tmp_path):
+    path = tmp_path / "req.txt"
+    path.write_text("GET / HTTP/1.1\r\nHost: FUZZ.target.com\r\nAccept: */*\r\n\r\nHost: body\r\n")
+    assert request_file_host(str(path)) == "FUZZ.target.com"
+    path.write_text("GET /FUZZ HTTP/1.1\nAccept: */*\n")
+    assert request_file_host(str(path)) == ""
+
+
+@pytest.mark.parametrize("target, bracketed", [
+    ("::1", "[::1]"), ("[::1]", "[::1]"), ("10.0.0.5", "10.0.0.5"), ("example.com", "example.com"),
+])
+def test_bracket_ipv6(target, bracketed):
+    assert bracket_ipv6(target) == bracketed
+
+
+@pytest.mark.parametrize("target, url", [
+    ("::1", "http://[::1]"), ("[::1]:8443", "https://[::1]:8443"), ("[2001:db8::5]:80", "http://[2001:db8::5]:80"),
+])
+def test_default_scheme_ipv6(target, url):
+    assert default_scheme(target) == url
+
+
+@pytest.mark.parametrize("target, url", [
+    ("10.0.0.5", "http://10.0.0.5"),
+    ("10.0.0.5:8443", "https://10.0.0.5:8443"),
+    ("10.0.0.5:443", "https://10.0.0.5:443"),
+    ("10.0.0.5:8080", "http://10.0.0.5:8080"),
+    ("intranet.test:443", "https://intranet.test:443"),
+])
+def test_default_scheme_ip_and_ip_port(target, url):
+    assert default_scheme(target) == url
+
+
+@pytest.mark.parametrize("target", ["http://10.0.0.5", "10.0.0.5/admin", "10.0.0.5:https", "10.0.0.5:99999"])
+def test_default_scheme_rejects(target):
+    with pytest.raises(ValueError):
+        default_scheme(target)
+
+
+@pytest.mark.parametrize("threads, rate, warns", [
+    (50, 200, False), (40, 20, False), (41, 20, True), (200, 10, True), (500, 0, False), (500, -1, False),
+])
+def test_check_rate_threads_threshold(threads, rate, warns):
+    warning = check_rate_threads(threads, rate)
+    assert bool(warning) == warns
+    if warns:
+        assert f"--threads {rate}" in warning
+
+
+def test_load_headers_skips_blanks_and_comments(tmp_path):
+    path = tmp_path / "headers.txt"
+    path.write_text("# auth\nAuthorization: Bearer abc:def\n\n  X-Test:  1  \nCookie:\n")
+
+    assert load_headers(str(path)) == ["Authorization: Bearer abc:def", "X-Test:  1", "Cookie:"]
+
+
+@pytest.mark.parametrize("text", ["X-Test: 1\nno colon here\n", "X-Test: 1\n: empty name\n"])
+def test_load_headers_names_the_bad_line(tmp_path, text):
+    path = tmp_path / "headers.txt"
+    path.write_text(text)
+
+    with pytest.raises(ValueError, match="line 2: expected 'Name: Value'"):
+        load_headers(str(path))
+
+
+def test_check_header_requires_a_name():
+    assert check_header("  Host: t.test ") == "Host: t.test"
+    with pytest.raises(ValueError):
+        check_header("Host t.test")
+
+
+def test_random_user_agent_comes_from_the_pool():
+    picks = [random_user_agent(random.Random(seed)) for seed in range(20)]
+
+    assert set(picks) <= set(USER_AGENTS)
+    assert len(set(picks)) > 1
+    assert random_user_agent(random.Random(7)) == random_user_agent(random.Random(7))
+
+
+@pytest.mark.parametrize("target, domain, warning", [
+    ("https://app.example.com", "example.com", "target host app.example.com is already a subdomain"),
+    ("APP.Example.com:8443", "example.com.", "target host app.example.com is already a subdomain"),
+    ("http://10.0.0.5", "10.0.0.5", "domain 10.0.0.5 is an IP address"),
+    ("http://[::1]", "[::1]", "domain [::1] is an IP address"),
+])
+def test_check_vhost_config_warns(target, domain, warning):
+    assert check_vhost_config(target, domain).startswith(warning)
+
+
+@pytest.mark.parametrize("target, domain", [
+    ("https://example.com", "example.com"),  # the normal setup
+    ("http://10.0.0.5:8080", "example.com"),
+    ("https://badexample.com", "example.com"),
+    ("https://example.com", ""),
+])
+def test_check_vhost_config_accepts(target, domain):
+    assert check_vhost_config(target, domain) == ""
+
+
+def test_expand_cidr_skips_network_and_broadcast():
+    assert expand_cidr("10.0.0.0/30") == ["http://10.0.0.1", "http://10.0.0.2"]
+    assert expand_cidr("10.0.0.6/30", "https", 8443) == ["https://10.0.0.5:8443", "https://10.0.0.6:8443"]
+
+
+@pytest.mark.parametrize("cidr, urls", [
+    ("10.0.0.4/31", ["http://10.0.0.4", "http://10.0.0.5"]),
+    ("10.0.0.9/32", ["http://10.0.0.9"]),
+    ("2001:db8::/127", ["http://[2001:db8::]", "http://[2001:db8::1]"]),
+])
+def test_expand_cidr_point_to_point_and_single_host(cidr, urls):
+    assert expand_cidr(cidr) == urls
+
+
+@pytest.mark.parametrize("cidr", ["10.0.0.0/8", "10.0.0.0/33", "not-a-network"])
+def test_expand_cidr_rejects(cidr):
+    with pytest.raises(ValueError):
+        expand_cidr(cidr)
+
+
+def test_expand_cidr_size_limit():
+    assert len(expand_cidr("10.0.0.0/20")) == MAX_CIDR_HOSTS - 2
+
+
+def _which(*installed):
+    return lambda name: f"/usr/bin/{name}" if name in installed else None
+
+
+@pytest.mark.parametrize("tool, platform, installed, hint", [
+    ("ffuf", "darwin", ["brew", "apt"], "brew install ffuf"),
+    ("dirb", "darwin", ["brew"], "install dirb from https://dirb.sourceforge.net/"),
+    ("ffuf", "linux", ["brew", "apt"], "sudo apt install ffuf"),  # Linuxbrew is not used
+    ("subfinder", "linux", ["apt"], "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest"),
+    ("gobuster", "linux", ["dnf", "pacman"], "go install github.com/OJ/gobuster/v3@latest"),  # first manager wins
+    ("gobuster", "linux", ["pacman"], "sudo pacman -S gobuster"),
+    ("wfuzz", "linux", [], "pip install wfuzz"),
+    ("newtool", "linux", ["apt"], "install newtool and make sure it is on PATH"),
+])
+def test_install_hint(tool, platform, installed, hint):
+    assert install_hint(tool, platform, _which(*installed)) == hint
+
+
+@pytest.mark.parametrize("line, summary", [
+    ('Get "https://t.test/": x509: certificate signed by unknown authority', "TLS certificate not trusted"),
+    ("[SSL: CERTIFICATE_VERIFY_FAILED] certificate verify failed: self-signed certificate",
+     "TLS certificate not trusted"),
+    ("tls: first record does not look like a TLS handshake", "TLS handshake failed"),
+    ("[SSL: WRONG_VERSION_NUMBER] wrong version number", "TLS handshake failed"),
+    ("dial tcp 10.0.0.5:443: connect: connection refused", "Connection refused"),
+    ("dial tcp 10.0.0.5:80: connect: no route to host", "Target unreachable"),
+    ("dial tcp: lookup nope.test: no such host", "Host name not found"),
+    ("Could not resolve host: nope.test", "Host name not found"),
+    ("dial tcp 10.0.0.5:80: i/o timeout", "Connection timed out"),
+    ("context deadline exceeded (Client.Timeout exceeded)", "Connection timed out"),
+])
+def test_classify_stderr_signatures(line, summary):
+    found, hint = classify_stderr(["Encountered an error:", line])
+    assert found == summary and hint
+
+
+def test_classify_stderr_most_specific_first_and_unknown():
+    assert classify_stderr(["i/o timeout", "x509: certificate has expired"])[0] == "TLS certificate not trusted"
+    assert classify_stderr(["unexpected EOF", ""]) == ("", "")
+    assert classify_stderr([]) == ("", "")
+
+
+def test_signed_header_is_the_hex_hmac_sha256():
+    # RFC 4231 test case 2
+    assert signed_header("Jefe", "what do ya want for nothing?") == (
+        "X-Signature: 5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+    )
+    assert signed_header("k", "https://t.test", "X-Api-Sig").startswith("X-Api-Sig: ")
+    assert signed_header("k", "https://a.test") != signed_header("k", "https://b.test")
@@ -0,0 +1,38 @@
+import json
+
+import pytest
+
+from krakenbuster.log import configure_logging, logger
+
+
+@pytest.fixture
+def restore_logger():
+    handlers, level, propagate = logger.handlers[:], logger.level, logger.propagate
+    yield
+    logger.handlers[:], logger.propagate = handlers, propagate
+    logger.setLevel(level)
+
+
+def test_level_filter(capsys, restore_logger):
+    configure_logging("warn", "json")
+    logger.info("not shown")
+    logger.warning("shown")
+
+    lines = capsys.readouterr().err.splitlines()
+    assert [json.loads(line)["message"] for line in lines] == ["shown"]
+
+
+def test_json_format_adds_fields(capsys, restore_logger):
+    configure_logging("debug", "json")
+    logger.debug("running %s", "ffuf -u x", extra={"fields": {"argv": ["ffuf", "-u", "x"]}})
+
+    entry = json.loads(capsys.readouterr().err)
+    assert entry["level"] == "debug"
+    assert entry["message"] == "running ffuf -u x"
+    assert entry["argv"] == ["ffuf", "-u", "x"]
+    assert entry["time"].endswith("Z")
+
+
+def test_unknown_level_rejected(restore_logger):
+    with pytest.raises(ValueError, match="log level"):
+        configure_logging("loud")
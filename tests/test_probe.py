@@ -0,0 +1,285 @@
+import random
+import socket
+import ssl
+import urllib.request
+from http.server import BaseHTTPRequestHandler
+
+import pytest
+
+from krakenbuster import probe
+from krakenbuster.output import Finding
+from krakenbuster.probe import (
+    Baseline,
+    detect_extensions,
+    new_http_client,
+    probe_baseline,
+    probe_vhost_baseline,
+    random_token,
+    resolve_scheme,
+    seed_from_robots,
+    seed_from_sitemap,
+    seed_random,
+)
+
+
+class _SoftNotFound(BaseHTTPRequestHandler):
+    """Answers every path with 200 and a page echoing the path."""
+
+    def do_GET(self):
+        body = f"<html>Sorry, {self.path} was not found</html>".encode()
+        self.send_response(200)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+def test_probe_baseline_records_soft_404(http_server):
+    soft_404_server = http_server(_SoftNotFound)
+    baseline = probe_baseline(soft_404_server, new_http_client({}), random.Random(1))
+
+    assert baseline.url.startswith(soft_404_server + "/")
+    assert baseline.status_code == 200
+    assert baseline.size == len(f"<html>Sorry, {baseline.url[len(soft_404_server):]} was not found</html>")
+    assert baseline.words == 5
+
+
+def test_probe_baseline_rejects_schemeless_target():
+    with pytest.raises(ValueError):
+        probe_baseline("target.com", new_http_client({}))
+
+
+@pytest.mark.parametrize("finding, matched", [
+    (Finding(status_code=200, size=50, words=5), True),
+    (Finding(status_code=200, size=61, words=5), True),   # longer echoed path
+    (Finding(status_code=200, size=61, words=0), False),  # tool gives no word count
+    (Finding(status_code=200, size=900, words=120), False),
+    (Finding(status_code=403, size=50, words=5), False),
+])
+def test_baseline_matches_status_and_size_or_words(finding, matched):
+    assert Baseline(status_code=200, size=50, words=5).matches(finding) is matched
+
+
+def _handler(client, kind):
+    return next((h for h in client.opener.handlers if isinstance(h, kind)), None)
+
+
+def test_http_client_uses_configured_proxy_and_tls():
+    client = new_http_client({
+        "proxy": "http://127.0.0.1:8080", "insecure": "true", "timeout": "3.5",
+        "headers": "X-Test: 1\nAuthorization: Bearer t",
+    })
+
+    assert _handler(client, urllib.request.ProxyHandler).proxies == {
+        "http": "http://127.0.0.1:8080", "https": "http://127.0.0.1:8080",
+    }
+    context = _handler(client, urllib.request.HTTPSHandler)._context
+    assert context.verify_mode == ssl.CERT_NONE and not context.check_hostname
+    assert client.timeout == 3.5
+    assert client.headers == {"X-Test": "1", "Authorization": "Bearer t"}
+
+
+def test_http_client_verifies_tls_by_default():
+    client = new_http_client({"timeout": "soon"})
+
+    context = _handler(client, urllib.request.HTTPSHandler)._context
+    assert context is None or context.verify_mode == ssl.CERT_REQUIRED
+    assert client.timeout == 10.0
+
+
+def test_resolve_scheme_falls_back_to_http(http_server):
+    soft_404_server = http_server(_SoftNotFound)
+    host = soft_404_server.removeprefix("http://")
+
+    assert resolve_scheme(host, new_http_client({"timeout": "2"})) == soft_404_server
+
+
+class _AnswersOver:
+    """A client stub that only gets a response for one scheme."""
+
+    def __init__(self, scheme):
+        self.scheme = scheme
+        self.tried = []
+
+    def get(self, url, max_bytes=0):
+        self.tried.append(url)
+        if not url.startswith(self.scheme + "://"):
+            raise ConnectionRefusedError("refused")
+
+
+def test_resolve_scheme_prefers_https():
+    client = _AnswersOver("https")
+
+    assert resolve_scheme("t.test", client) == "https://t.test"
+    assert client.tried == ["https://t.test"]
+
+
+def test_resolve_scheme_fails_when_nothing_answers():
+    with socket.socket() as sock:
+        sock.bind(("127.0.0.1", 0))
+        port = sock.getsockname()[1]
+
+    with pytest.raises(OSError, match="over https or http"):
+        resolve_scheme(f"127.0.0.1:{port}", new_http_client({"timeout": "2"}))
+
+
+class _CatchAllVhost(BaseHTTPRequestHandler):
+    """Answers any Host header with a 200 welcome page naming that host."""
+
+    def do_GET(self):
+        body = f"<html>Welcome to {self.headers['Host']}</html>".encode()
+        self.send_response(200)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+def test_probe_vhost_baseline_sends_a_random_subdomain(http_server):
+    catch_all_server = http_server(_CatchAllVhost)
+    client = new_http_client({})
+    baseline = probe_vhost_baseline(catch_all_server, "t.test", client, random.Random(1))
+
+    assert baseline.url.endswith(".t.test") and len(baseline.url) == len("x" * 16 + ".t.test")
+    assert baseline.status_code == 200
+    assert baseline.size == len(f"<html>Welcome to {baseline.url}</html>")
+    assert baseline.words == 3
+    assert probe_vhost_baseline(catch_all_server, "t.test", client, random.Random(1)) == baseline
+    assert probe_vhost_baseline(catch_all_server, "t.test", client, random.Random(2)).url != baseline.url
+
+
+class _Stacks(BaseHTTPRequestHandler):
+    """Answers each path with the headers and body of a different stack."""
+
+    pages = {
+        "/php": ([("X-Powered-By", "PHP/8.2.1")], "<html>hi</html>"),
+        "/asp": ([("Server", "Microsoft-IIS/10.0"), ("Set-Cookie", "ASP.NET_SessionId=abc; path=/")],
+                 '<input type="hidden" name="__VIEWSTATE" value="x">'),
+        "/plain": ([("Server", "nginx")], "<html>static</html>"),
+    }
+
+    def do_GET(self):
+        headers, body = self.pages[self.path]
+        self.send_response(200)
+        for name, value in headers:
+            self.send_header(name, value)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body.encode())
+
+    def log_message(self, *args):
+        pass
+
+
+@pytest.mark.parametrize("path, technologies, extensions", [
+    ("/php", ["PHP"], ["php"]),
+    ("/asp", ["ASP.NET"], ["aspx", "asp", "ashx", "asmx"]),
+    ("/plain", [], []),
+])
+def test_detect_extensions_from_headers_and_body(http_server, path, technologies, extensions):
+    stacks_server = http_server(_Stacks)
+    assert detect_extensions(stacks_server + path, new_http_client({})) == (technologies, extensions)
+
+
+def test_same_seed_gives_the_same_probe_path(http_server, monkeypatch):
+    soft_404_server = http_server(_SoftNotFound)
+    monkeypatch.setattr(probe, "rng", random.Random())
+    client = new_http_client({})
+
+    seed_random(1337)
+    first = probe_baseline(soft_404_server, client)
+    seed_random(1337)
+    second = probe_baseline(soft_404_server, client)
+    seed_random(1338)
+    other = probe_baseline(soft_404_server, client)
+
+    assert first.url == second.url != other.url
+
+
+def test_random_token_length_and_source():
+    assert len(random_token(random.Random(1))) == 32
+    assert random_token(random.Random(1), 8) == random_token(random.Random(1), 8)
+
+
+class _Versions(BaseHTTPRequestHandler):
+    """Records the HTTP version of each request."""
+
+    protocol_version = "HTTP/1.1"
+    seen: list[str] = []
+
+    def do_GET(self):
+        type(self).seen.append(self.request_version)
+        self.send_response(204)
+        self.send_header("Content-Length", "0")
+        self.end_headers()
+
+    def log_message(self, *args):
+        pass
+
+
+def test_internal_client_stays_on_http1_with_http2_set(http_server):
+    new_http_client({"http2": "true"}).get(http_server(_Versions) + "/", max_bytes=0)
+
+    assert _Versions.seen == ["HTTP/1.1"]
+
+
+ROBOTS = """\
+User-agent: *
+Disallow: /admin/
+Disallow: /app/private/*.bak$
+Allow: /app/public
+disallow: /admin
+Disallow: /
+# Disallow: /commented
+Sitemap: /sitemap.xml
+"""
+
+SITEMAP = """\
+<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>http://{host}/about</loc></url>
+  <url><loc>http://{host}/app/docs/</loc></url>
+  <url><loc> http://{host}/about </loc></url>
+  <url><loc>http://elsewhere.test/offsite</loc></url>
+</urlset>
+"""
+
+
+class _Seeds(BaseHTTPRequestHandler):
+    """Serves ROBOTS and SITEMAP, and a 404 for any other path."""
+
+    def do_GET(self):
+        pages = {"/robots.txt": ROBOTS, "/sitemap.xml": SITEMAP.format(host=self.headers["Host"])}
+        body = pages.get(self.path, "not found").encode()
+        self.send_response(200 if self.path in pages else 404)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+def test_seed_from_robots_parses_allow_and_disallow_rules(http_server):
+    seeds_server = http_server(_Seeds)
+    client = new_http_client({})
+    assert seed_from_robots(seeds_server, client) == ["admin", "app/private", "app/public"]
+    assert seed_from_robots(f"{seeds_server}/app/", client) == ["private", "public"]
+
+
+def test_seed_from_sitemap_keeps_same_host_locations(http_server):
+    seeds_server = http_server(_Seeds)
+    client = new_http_client({})
+    assert seed_from_sitemap(seeds_server, client) == ["about", "app/docs"]
+    assert seed_from_sitemap(f"{seeds_server}/app", client) == ["docs"]
+
+
+def test_seeds_are_empty_when_robots_and_sitemap_are_not_real(http_server):
+    soft_404_server = http_server(_SoftNotFound)
+    client = new_http_client({})
+    assert seed_from_robots(soft_404_server, client) == []
+    assert seed_from_sitemap(soft_404_server, client) == []
@@ -0,0 +1,92 @@
+import os
+from pathlib import Path
+
+import pytest
+
+from krakenbuster.scanners.base import create_scanner
+from krakenbuster.scanners.feroxbuster import latest_state_file
+
+
+def _ffuf(mode="directory", **options):
+    return create_scanner("ffuf", mode, "https://t.test", "/lists/words.txt", options).build_command()
+
+
+def test_ffuf_keyword_wordlists_become_w_pairs():
+    command = _ffuf(wordlist_keywords="/lists/a.txt:W1\n/lists/b.txt:W2", ffuf_mode="pitchfork")
+
+    assert command[command.index("-w") + 1] == "/lists/words.txt"
+    pairs = [command[i + 1] for i, arg in enumerate(command) if arg == "-w"]
+    assert pairs == ["/lists/words.txt", "/lists/a.txt:W1", "/lists/b.txt:W2"]
+    assert command[command.index("-mode") + 1] == "pitchfork"
+
+
+def test_ffuf_mode_needs_keyword_wordlists():
+    assert "-mode" not in _ffuf(ffuf_mode="clusterbomb")
+
+
+def test_ffuf_http2_flag_in_every_mode():
+    assert "-http2" in _ffuf(http2="true")
+    assert "-http2" in _ffuf("vhost", http2="true", domain="t.test")
+    assert "-http2" not in _ffuf(http2="false")
+
+
+def test_ffuf_request_file_replaces_url_and_headers():
+    command = _ffuf(request_file="/tmp/req.txt", headers="X-Test: 1", extensions="php")
+
+    assert command[:5] == ["ffuf", "-request", "/tmp/req.txt", "-request-proto", "https"]
+    assert "-u" not in command and "-H" not in command and "-e" not in command
+    assert command[command.index("-w") + 1] == "/lists/words.txt"
+
+
+def test_extra_args_appended_last():
+    command = create_scanner(
+        "feroxbuster", "directory", "https://t.test", "/lists/words.txt",
+        {"extra_args": "--collect-backups -C 500"},
+    ).build_command()
+    assert command[-3:] == ["--collect-backups", "-C", "500"]
+
+
+def test_ffuf_replay_proxy_only_when_set():
+    proxy = "http://127.0.0.1:8080"
+    command = _ffuf(replay_proxy=proxy, proxy="http://10.0.0.1:3128")
+    assert command[command.index("-replay-proxy") + 1] == proxy
+    assert command[command.index("-x") + 1] == "http://10.0.0.1:3128"
+    assert "-replay-proxy" in _ffuf("vhost", replay_proxy=proxy, domain="t.test")
+    assert "-replay-proxy" not in _ffuf()
+    assert "-replay-proxy" not in _ffuf(replay_proxy="")
+
+
+def _ferox(**options):
+    return create_scanner("feroxbuster", "directory", "https://t.test", "lists/words.txt", options)
+
+
+def test_feroxbuster_fresh_and_resumed_argv(tmp_path):
+    fresh = _ferox().build_command()
+    assert "--no-state" in fresh and "--resume-from" not in fresh
+
+    # Resumable but nothing to resume yet: keeps state, in the state directory
+    first = _ferox(resume="true", state_dir=str(tmp_path))
+    command = first.build_command()
+    assert "--no-state" not in command
+    assert command[command.index("-w") + 1] == str(Path("lists/words.txt").resolve())
+    assert first.working_directory() == str(tmp_path)
+
+    state = str(tmp_path / "ferox-t_test-1.state")
+    assert _ferox(resume="true", state_dir=str(tmp_path), resume_from=state).build_command() == [
+        "feroxbuster", "--resume-from", state,
+    ]
+
+
+def test_latest_state_file(tmp_path):
+    assert latest_state_file(str(tmp_path / "missing")) == ""
+    old, new = tmp_path / "ferox-a-1.state", tmp_path / "ferox-a-2.state"
+    old.write_text("{}")
+    new.write_text("{}")
+    os.utime(old, (1, 1))
+    assert latest_state_file(str(tmp_path)) == str(new)
+
+
+@pytest.mark.parametrize("depth", ["0", "1"])
+def test_feroxbuster_depth_argv(depth):
+    command = _ferox(depth=depth).build_command()
+    assert command[command.index("-d") + 1] == depth
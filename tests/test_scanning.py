@@ -0,0 +1,89 @@
+from types import SimpleNamespace
+
+import pytest
+
+from krakenbuster.screens import scanning
+from krakenbuster.screens.scanning import ScanningScreen
+
+
+@pytest.mark.parametrize("scan_type, tools, runs_vhost", [
+    ("directory", {"feroxbuster": True, "ffuf": True}, False),
+    ("combined", {"feroxbuster": True, "ffuf": True}, True),
+    ("combined", {"feroxbuster": True, "ffuf": False}, False),
+    ("combined", {"feroxbuster": False, "ffuf": True}, True),
+])
+def test_combined_layout_follows_the_installed_vhost_tool(scan_type, tools, runs_vhost):
+    app = SimpleNamespace(scan_type=scan_type, selected_vhost_tool="ffuf", available_tools=tools)
+    assert ScanningScreen._runs_vhost(SimpleNamespace(app=app)) is runs_vhost
+
+
+class _Scanner:
+    def __init__(self, running=True):
+        self.running = running
+        self.signals = []
+
+    def pause(self):
+        self.signals.append("stop")
+        return self.running
+
+    def resume(self):
+        self.signals.append("cont")
+        return self.running
+
+
+def _screen(scanner, vhost_scanner=None):
+    screen = SimpleNamespace(
+        _scanner=scanner, _vhost_scanner=vhost_scanner, _start_time=0.0,
+        _paused_since=None, _paused_total=0.0, notes=[],
+    )
+    screen.notify = lambda message, **kwargs: screen.notes.append(message)
+    screen._refresh_stats = lambda: None
+    screen._active_elapsed = lambda: ScanningScreen._active_elapsed(screen)
+    return screen
+
+
+def test_pause_toggle_suspends_and_resumes_every_tool(monkeypatch):
+    clock = iter([10.0, 14.0, 20.0])
+    monkeypatch.setattr(scanning.time, "time", lambda: next(clock))
+    dir_scanner, vhost_scanner = _Scanner(), _Scanner()
+    screen = _screen(dir_scanner, vhost_scanner)
+
+    ScanningScreen.action_toggle_pause(screen)
+    assert screen._paused_since == 10.0
+    ScanningScreen.action_toggle_pause(screen)
+    assert screen._paused_since is None and screen._paused_total == 4.0
+    assert dir_scanner.signals == vhost_scanner.signals == ["stop", "cont"]
+    assert screen.notes == ["Scan paused, press p to resume", "Scan resumed"]
+    assert screen._active_elapsed() == 16.0
+
+
+def test_pause_with_nothing_running_stays_unpaused():
+    screen = _screen(_Scanner(running=False))
+
+    ScanningScreen.action_toggle_pause(screen)
+
+    assert screen._paused_since is None
+    assert screen.notes == ["Nothing to pause"]
+
+
+class _Columns:
+    def __init__(self):
+        self.classes = set()
+
+    def set_class(self, add, name):
+        (self.classes.add if add else self.classes.discard)(name)
+
+
+def test_resize_reflows_between_stacked_and_side_by_side():
+    columns = _Columns()
+    screen = SimpleNamespace(query_one=lambda selector, kind: columns)
+    screen._apply_layout = lambda width: ScanningScreen._apply_layout(screen, width)
+
+    def resize(width):
+        ScanningScreen.on_resize(screen, SimpleNamespace(size=SimpleNamespace(width=width, height=40)))
+        return "stacked" in columns.classes
+
+    assert resize(scanning.STACKED_WIDTH - 1) is True
+    assert resize(scanning.STACKED_WIDTH) is False
+    assert resize(60) is True
+    assert resize(200) is False
@@ -0,0 +1,15 @@
+import pytest
+
+from krakenbuster.screens.target import validate_target
+
+
+@pytest.mark.parametrize("target", [
+    "http://[::1]:8080/", "https://[2001:db8::5]", "http://[::ffff:10.0.0.5]/admin",
+])
+def test_validate_target_accepts_bracketed_ipv6(target):
+    assert validate_target(target, "directory") is None
+
+
+@pytest.mark.parametrize("target", ["http://[::1", "http://[zz::1]/"])
+def test_validate_target_rejects_broken_ipv6(target):
+    assert validate_target(target, "directory") == "Invalid URL format"
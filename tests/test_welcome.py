@@ -0,0 +1,17 @@
+import pytest
+
+from krakenbuster.screens.welcome import BANNER, COMPACT_BANNER, COMPACT_BANNER_WIDTH, banner
+
+
+@pytest.mark.parametrize("width, expected", [
+    (40, COMPACT_BANNER), (COMPACT_BANNER_WIDTH - 1, COMPACT_BANNER), (COMPACT_BANNER_WIDTH, BANNER), (120, BANNER),
+])
+def test_banner_for_width(monkeypatch, width, expected):
+    monkeypatch.delenv("NO_COLOR", raising=False)
+    assert banner(width) == expected
+
+
+def test_no_color_gets_the_compact_banner(monkeypatch):
+    monkeypatch.setenv("NO_COLOR", "1")
+    assert banner(120) == COMPACT_BANNER
+    assert "\x1b" not in COMPACT_BANNER and "[" not in COMPACT_BANNER
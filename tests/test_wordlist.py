@@ -0,0 +1,262 @@
+import io
+import os
+import random
+from http.server import BaseHTTPRequestHandler
+from pathlib import Path
+
+import pytest
+
+from krakenbuster import config, wordlist
+from krakenbuster.probe import new_http_client
+from krakenbuster.screens.wordlist import restore_browser_state
+from krakenbuster.wordlist import WordlistFile, fetch_wordlist
+
+
+class _Lists(BaseHTTPRequestHandler):
+    """Serves a small list at /words.txt and an empty one at /empty.txt."""
+
+    hits = 0
+
+    def do_GET(self):
+        type(self).hits += 1
+        bodies = {"/words.txt": b"admin\nlogin\n", "/empty.txt": b"\n"}
+        body = bodies.get(self.path)
+        self.send_response(200 if body is not None else 404)
+        self.end_headers()
+        self.wfile.write(body or b"")
+
+    def log_message(self, *args):
+        pass
+
+
+@pytest.fixture
+def cache_dir(tmp_path, monkeypatch):
+    path = tmp_path / "cache" / "wordlists"
+    monkeypatch.setattr(wordlist, "WORDLIST_CACHE_DIR", path)
+    return path
+
+
+def test_fetch_wordlist_caches_by_url(http_server, cache_dir):
+    _Lists.hits = 0
+    list_server = http_server(_Lists)
+    client = new_http_client({})
+    first = fetch_wordlist(f"{list_server}/words.txt", client)
+    second = fetch_wordlist(f"{list_server}/words.txt", client)
+
+    assert first == second
+    assert open(first).read() == "admin\nlogin\n"
+    assert _Lists.hits == 1
+    assert cache_dir.stat().st_mode & 0o777 == 0o700
+
+
+@pytest.mark.parametrize("path, message", [("/empty.txt", "empty"), ("/missing.txt", "HTTP 404")])
+def test_fetch_wordlist_rejects_bad_lists(http_server, cache_dir, path, message):
+    list_server = http_server(_Lists)
+    with pytest.raises(ValueError, match=message):
+        fetch_wordlist(list_server + path, new_http_client({}))
+
+
+def test_cache_is_per_user(monkeypatch, tmp_path):
+    monkeypatch.setenv("XDG_CACHE_HOME", str(tmp_path))
+    assert wordlist._user_cache_dir() == tmp_path / "krakenbuster"
+    monkeypatch.setenv("XDG_CACHE_HOME", "relative")
+    assert wordlist._user_cache_dir().parent.name == ".cache"
+
+
+def test_parallel_discovery_matches_a_sequential_walk(tmp_path):
+    roots = [tmp_path / name for name in ("seclists", "dirb", "missing", "empty", "dirbuster")]
+    for root, files in zip(roots, (["a.txt", "web/b.txt", "web/deep/c.lst"], ["common.txt"], [], [], ["x.txt"])):
+        if root.name != "missing":
+            root.mkdir()
+        for name in files:
+            (root / name).parent.mkdir(parents=True, exist_ok=True)
+            (root / name).write_text("admin\n")
+    options = wordlist.DiscoverOptions()
+
+    sequential_mtimes: dict[str, float] = {}
+    sequential = [wordlist._scan_directory(root, options, sequential_mtimes) for root in roots]
+    sequential = [d for d in sequential if d and d.total_count > 0]
+    mtimes: dict[str, float] = {}
+
+    assert wordlist._scan_roots(roots, options, mtimes) == sequential
+    assert mtimes == sequential_mtimes
+
+
+def test_combine_wordlists_dedupes_in_first_seen_order(tmp_path):
+    first, second = tmp_path / "a.txt", tmp_path / "b.txt"
+    first.write_text("admin\nlogin\n\nbackup\n")
+    second.write_text("login\r\nconfig\nadmin\nuploads\n")
+
+    path, cleanup = wordlist.combine_wordlists([str(first), str(second)])
+    try:
+        assert open(path).read().splitlines() == ["admin", "login", "backup", "config", "uploads"]
+    finally:
+        cleanup()
+    assert not os.path.exists(path)
+
+
+def test_combine_single_wordlist_is_unchanged(tmp_path):
+    only = tmp_path / "a.txt"
+    only.write_text("admin\nadmin\n")
+    path, cleanup = wordlist.combine_wordlists([str(only)])
+    cleanup()
+    assert path == str(only) and only.exists()
+
+
+def test_discovery_cache_is_reused_until_a_root_changes(tmp_path, monkeypatch):
+    monkeypatch.setattr(wordlist, "DISCOVERY_CACHE_PATH", tmp_path / "cache.json")
+    walks = []
+    scan_roots = wordlist._scan_roots
+    monkeypatch.setattr(wordlist, "_scan_roots", lambda *a: walks.append(a) or scan_roots(*a))
+    root, missing = tmp_path / "lists", tmp_path / "later"
+    (root / "web").mkdir(parents=True)
+    (root / "web" / "common.txt").write_text("admin\n")
+
+    first = wordlist.discover_wordlists_cached([root, missing])
+    assert wordlist.discover_wordlists_cached([root, missing]) == first
+    assert len(walks) == 1
+
+    (root / "web" / "big.txt").write_text("admin\n")
+    os.utime(root / "web", (1, 1))
+    changed = wordlist.discover_wordlists_cached([root, missing])
+    assert len(walks) == 2
+    assert changed[0].total_count == 2
+
+    missing.mkdir()
+    wordlist.discover_wordlists_cached([root, missing])
+    assert len(walks) == 3
+
+
+@pytest.mark.parametrize("name", ["common.txt", "names.lst", "words.dic", "UPPER.TXT"])
+def test_wordlist_extensions_are_accepted(tmp_path, name):
+    path = tmp_path / name
+    path.write_text("admin\n")
+    assert wordlist.DiscoverOptions().accepts(path)
+
+
+@pytest.mark.parametrize("name", ["readme.md", "archive.tar.gz", "notes.json"])
+def test_other_extensions_are_not(tmp_path, name):
+    path = tmp_path / name
+    path.write_text("admin\n")
+    assert not wordlist.DiscoverOptions().accepts(path)
+
+
+@pytest.mark.parametrize("content, accepted", [(b"admin\nlogin\n", True), (b"\x7fELF\x00\x01", False), (b"", False)])
+def test_extensionless_files_need_the_option_and_text(tmp_path, content, accepted):
+    path = tmp_path / "words"
+    path.write_bytes(content)
+    assert not wordlist.DiscoverOptions().accepts(path)
+    assert wordlist.DiscoverOptions(include_extensionless=True).accepts(path) is accepted
+
+
+def test_check_wordlist_path_rejects_a_directory(tmp_path):
+    with pytest.raises(ValueError, match="is a directory, not a file"):
+        wordlist.check_wordlist_path(str(tmp_path))
+    with pytest.raises(OSError):
+        wordlist.check_wordlist_path(str(tmp_path / "missing.txt"))
+
+    words = tmp_path / "words.txt"
+    words.write_text("admin\n")
+    wordlist.check_wordlist_path(str(words))
+
+
+def test_stdin_wordlist_is_copied_to_a_temporary_file():
+    path, cleanup = wordlist.read_stdin_wordlist(io.BytesIO(b"admin\nlogin\n"))
+    try:
+        with open(path, "rb") as fh:
+            assert fh.read() == b"admin\nlogin\n"
+    finally:
+        cleanup()
+    assert not os.path.exists(path)
+
+
+def test_empty_stdin_wordlist_is_rejected(tmp_path, monkeypatch):
+    monkeypatch.setattr(wordlist.tempfile, "tempdir", str(tmp_path))
+    with pytest.raises(ValueError, match="stdin is empty"):
+        wordlist.read_stdin_wordlist(io.BytesIO(b"\n  \n"))
+    assert list(tmp_path.iterdir()) == []
+
+
+def test_persisted_filter_and_selection_apply_to_a_fresh_list(tmp_path, monkeypatch):
+    monkeypatch.setattr(config, "CONFIG_PATH", tmp_path / "krakenbuster.conf")
+    saved = config.load_config()
+    saved["wordlists"]["last_filter"] = "Common"
+    saved["wordlists"]["last_used"] = str(tmp_path / "web" / "common.txt")
+    config.save_config(saved)
+
+    loaded = config.load_config()
+    last_filter = loaded.get("wordlists", "last_filter")
+    last_used = loaded.get("wordlists", "last_used")
+    files = [WordlistFile(tmp_path / "web" / "common.txt", size=1), WordlistFile(tmp_path / "big.txt", size=1)]
+
+    assert restore_browser_state(last_filter, last_used, files) == ("Common", last_used)
+
+
+def test_persisted_state_is_dropped_when_the_lists_changed(tmp_path):
+    files = [WordlistFile(tmp_path / "big.txt", size=1)]
+
+    assert restore_browser_state("common", str(tmp_path / "common.txt"), files) == ("", "")
+    assert restore_browser_state("BIG", str(tmp_path / "gone.txt"), files) == ("BIG", "")
+
+
+def _entries(path) -> list[bytes]:
+    with open(path, "rb") as fh:
+        return fh.read().splitlines()
+
+
+def test_shuffle_keeps_the_same_entries(tmp_path):
+    source = tmp_path / "words.txt"
+    words = [f"word{i}".encode() for i in range(200)]
+    source.write_bytes(b"\r\n".join(words[:100]) + b"\n\n" + b"\n".join(words[100:]))
+
+    path, cleanup = wordlist.shuffle_wordlist(str(source), random.Random(3))
+    try:
+        shuffled = _entries(path)
+    finally:
+        cleanup()
+    assert sorted(shuffled) == sorted(words)
+    assert shuffled != words
+
+    again, cleanup = wordlist.shuffle_wordlist(str(source), random.Random(3))
+    try:
+        assert _entries(again) == shuffled
+    finally:
+        cleanup()
+
+
+@pytest.mark.parametrize("limit, kept", [(2, [b"admin", b"login"]), (10, [b"admin", b"login", b"backup"])])
+def test_wordlist_limit_takes_the_first_entries(tmp_path, limit, kept):
+    source = tmp_path / "words.txt"
+    source.write_bytes(b"\nadmin\r\n\nlogin\nbackup")
+
+    path, cleanup = wordlist.head_wordlist(str(source), limit)
+    try:
+        assert _entries(path) == kept
+    finally:
+        cleanup()
+    assert not os.path.exists(path)
+
+
+def _paths(files: list[WordlistFile]) -> list[str]:
+    return [str(wf.path) for wf in files]
+
+
+def test_php_target_ranks_php_lists_first():
+    files = [WordlistFile(Path(p), size=1) for p in (
+        "/wl/big.txt", "/wl/common.txt", "/wl/CMS/wordpress.fuzz.txt", "/wl/aspx.txt", "/wl/PHP.fuzz.txt",
+    )]
+
+    ranked = wordlist.rank_for_target(files, ["PHP"])
+
+    assert _paths(ranked) == [
+        "/wl/CMS/wordpress.fuzz.txt", "/wl/PHP.fuzz.txt", "/wl/common.txt", "/wl/big.txt", "/wl/aspx.txt",
+    ]
+    assert _paths(wordlist.rank_for_target(files, [])) == [
+        "/wl/common.txt", "/wl/big.txt", "/wl/CMS/wordpress.fuzz.txt", "/wl/aspx.txt", "/wl/PHP.fuzz.txt",
+    ]
+
+
+def test_target_relevance_counts_each_matching_technology():
+    path = Path("/wl/php-and-aspx.txt")
+    assert wordlist.target_relevance(path, ["PHP", "ASP.NET", "Java"]) == 2
+    assert wordlist.target_relevance(path, ["Unknown"]) == 0